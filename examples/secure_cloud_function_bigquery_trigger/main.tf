@@ -16,8 +16,8 @@
 
 
 locals {
-  location        = "us-west1"
-  region          = "us-west1"
+  location        = var.location
+  region          = var.location
   repository_name = "rep-secure-cloud-function"
   table_name      = "tbl_test"
   kms_bigquery    = "key-secure-bigquery"
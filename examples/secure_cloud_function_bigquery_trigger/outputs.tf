@@ -102,3 +102,8 @@ output "cloudfunction_url" {
   value       = module.secure_cloud_function.cloudfunction_url
   description = "The URL on which the deployed service is available."
 }
+
+output "location" {
+  value       = local.location
+  description = "The location where the resources were deployed."
+}
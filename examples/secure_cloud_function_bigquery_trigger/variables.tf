@@ -68,3 +68,9 @@ variable "ingress_policies" {
   }))
   default = []
 }
+
+variable "location" {
+  description = "The location where the resources are going to be deployed."
+  type        = string
+  default     = "us-west1"
+}
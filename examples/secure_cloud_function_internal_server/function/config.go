@@ -0,0 +1,168 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultScheme = "http"
+	defaultPort   = "8000"
+	defaultPath   = "/index.html"
+)
+
+// upstream is the internal server the function calls.
+type upstream struct {
+	// base is the URL of the internal server. Its path and query are the
+	// prefix of every request when forwardPath is set.
+	base *url.URL
+	// forwardPath appends the path and query of the incoming request to base.
+	forwardPath bool
+}
+
+// loadUpstream reads the upstream configuration from the environment.
+// TARGET_URL takes precedence over TARGET_SCHEME, TARGET_IP, TARGET_PORT and
+// TARGET_PATH, which default to http://<TARGET_IP>:8000/index.html.
+func loadUpstream(getenv func(string) string) (upstream, error) {
+	var u upstream
+	forward, err := boolEnv(getenv, "FORWARD_REQUEST_PATH")
+	if err != nil {
+		return u, err
+	}
+	u.forwardPath = forward
+
+	raw := getenv("TARGET_URL")
+	if raw == "" {
+		host := getenv("TARGET_IP")
+		if host == "" {
+			return u, fmt.Errorf("either TARGET_URL or TARGET_IP must be set")
+		}
+		raw = (&url.URL{
+			Scheme: orDefault(getenv("TARGET_SCHEME"), defaultScheme),
+			Host:   net.JoinHostPort(host, orDefault(getenv("TARGET_PORT"), defaultPort)),
+			Path:   orDefault(getenv("TARGET_PATH"), defaultPath),
+		}).String()
+	}
+
+	base, err := url.Parse(raw)
+	if err != nil {
+		return u, fmt.Errorf("invalid upstream URL %q: %v", raw, err)
+	}
+	if err := validateURL(base); err != nil {
+		return u, fmt.Errorf("invalid upstream URL %q: %v", base.Redacted(), err)
+	}
+	u.base = base
+	return u, nil
+}
+
+func validateURL(u *url.URL) error {
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("scheme must be http or https")
+	case u.Hostname() == "":
+		return fmt.Errorf("host is empty")
+	case u.User != nil:
+		return fmt.Errorf("credentials are not allowed")
+	case u.Fragment != "":
+		return fmt.Errorf("fragments are not allowed")
+	case u.Path != "" && !strings.HasPrefix(u.Path, "/"):
+		return fmt.Errorf("path must be absolute")
+	}
+	if p := u.Port(); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("port %q is out of range", p)
+		}
+	}
+	return nil
+}
+
+// target returns the upstream URL for an incoming request with the given path and query.
+func (u upstream) target(path, rawQuery string) *url.URL {
+	t := *u.base
+	if !u.forwardPath {
+		return &t
+	}
+	t.Path = joinPath(u.base.Path, path)
+	t.RawPath = ""
+	switch {
+	case u.base.RawQuery == "":
+		t.RawQuery = rawQuery
+	case rawQuery != "":
+		t.RawQuery = u.base.RawQuery + "&" + rawQuery
+	}
+	return &t
+}
+
+// joinPath joins a and b with exactly one slash, like httputil.NewSingleHostReverseProxy.
+func joinPath(a, b string) string {
+	if b == "" || b == "/" {
+		if a == "" {
+			return "/"
+		}
+		return a
+	}
+	return strings.TrimSuffix(a, "/") + "/" + strings.TrimPrefix(b, "/")
+}
+
+// boolEnv parses the environment variable name, which defaults to false.
+func boolEnv(getenv func(string) string, name string) (bool, error) {
+	v := getenv(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s %q is not a boolean", name, v)
+	}
+	return b, nil
+}
+
+func durationEnv(getenv func(string) string, name string, def time.Duration) (time.Duration, error) {
+	v := getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s %q is not a valid duration", name, v)
+	}
+	return d, nil
+}
+
+func intEnv(getenv func(string) string, name string, def int) (int, error) {
+	v := getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %q is not a valid count", name, v)
+	}
+	return n, nil
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
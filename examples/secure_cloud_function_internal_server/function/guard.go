@@ -0,0 +1,202 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	// errDenied is returned for calls to an upstream outside the allowlist.
+	errDenied = errors.New("upstream not allowed")
+	// errRedirect is returned when the upstream redirects to another host.
+	errRedirect = errors.New("redirect to another host refused")
+)
+
+// allowlist is the set of upstreams the function may call. It mirrors the
+// fw-e-shared-restricted-internal-server egress firewall rule, so that a
+// forwarded path or a DNS answer can't turn the function into a relay to any
+// other address of the restricted VPC.
+type allowlist struct {
+	prefixes []netip.Prefix
+	ports    []int
+	paths    []string
+}
+
+// loadAllowlist reads the allowlist from the environment and checks that it allows u.
+// ALLOWED_UPSTREAM_CIDRS defaults to the upstream IP, and is required when the
+// upstream is a host name. ALLOWED_UPSTREAM_PORTS defaults to the upstream port
+// and ALLOWED_PATH_PREFIXES to every path.
+func loadAllowlist(getenv func(string) string, u upstream) (allowlist, error) {
+	var a allowlist
+	cidrs := splitList(getenv("ALLOWED_UPSTREAM_CIDRS"))
+	if len(cidrs) == 0 {
+		ip, err := netip.ParseAddr(u.base.Hostname())
+		if err != nil {
+			return a, fmt.Errorf("ALLOWED_UPSTREAM_CIDRS must be set when the upstream host %q is not an IP address", u.base.Hostname())
+		}
+		cidrs = []string{netip.PrefixFrom(ip, ip.BitLen()).String()}
+	}
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return a, fmt.Errorf("ALLOWED_UPSTREAM_CIDRS %q is not a CIDR range", c)
+		}
+		a.prefixes = append(a.prefixes, p.Masked())
+	}
+
+	ports := splitList(getenv("ALLOWED_UPSTREAM_PORTS"))
+	if len(ports) == 0 {
+		ports = []string{port(u.base)}
+	}
+	for _, p := range ports {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return a, fmt.Errorf("ALLOWED_UPSTREAM_PORTS %q is not a port", p)
+		}
+		a.ports = append(a.ports, n)
+	}
+
+	a.paths = splitList(getenv("ALLOWED_PATH_PREFIXES"))
+	for _, p := range a.paths {
+		if !strings.HasPrefix(p, "/") {
+			return a, fmt.Errorf("ALLOWED_PATH_PREFIXES %q is not an absolute path", p)
+		}
+	}
+
+	err := a.checkURL(u.base)
+	if ip, parseErr := netip.ParseAddr(u.base.Hostname()); err == nil && parseErr == nil {
+		err = a.checkAddr(net.JoinHostPort(ip.String(), port(u.base)))
+	}
+	if err != nil {
+		return a, fmt.Errorf("the allowlist denies the upstream itself: %v", err)
+	}
+	return a, nil
+}
+
+// checkURL returns an error wrapping errDenied unless the port and path of u are allowed.
+// The host is checked once resolved, by control.
+func (a allowlist) checkURL(u *url.URL) error {
+	p, _ := strconv.Atoi(port(u))
+	if !slices.Contains(a.ports, p) {
+		return fmt.Errorf("%w: port %d of %s", errDenied, p, u.Redacted())
+	}
+	if len(a.paths) == 0 {
+		return nil
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("%w: dot segments in path %q", errDenied, u.Path)
+		}
+	}
+	for _, prefix := range a.paths {
+		if hasPathPrefix(u.Path, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: path %q", errDenied, u.Path)
+}
+
+// checkAddr returns an error wrapping errDenied unless address, an IP and port, is allowed.
+func (a allowlist) checkAddr(address string) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: address %q: %v", errDenied, address, err)
+	}
+	ip := ap.Addr().Unmap()
+	if !slices.ContainsFunc(a.prefixes, func(p netip.Prefix) bool { return p.Contains(ip) }) {
+		return fmt.Errorf("%w: address %s", errDenied, ip)
+	}
+	if !slices.Contains(a.ports, int(ap.Port())) {
+		return fmt.Errorf("%w: port %d of %s", errDenied, ap.Port(), ip)
+	}
+	return nil
+}
+
+// control is a net.Dialer Control function that checks every connection after
+// DNS resolution, so that a host name can't resolve to an address outside the allowlist.
+func (a allowlist) control(network, address string, _ syscall.RawConn) error {
+	if err := a.checkAddr(address); err != nil {
+		log.Printf("Denied connection: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// checkRedirect returns errRedirect when location, relative to from, points to another host.
+func checkRedirect(from *url.URL, location string) error {
+	to, err := from.Parse(location)
+	if err != nil {
+		return fmt.Errorf("%w: invalid location %q", errRedirect, location)
+	}
+	if to.Scheme != from.Scheme || to.Host != from.Host {
+		return fmt.Errorf("%w: %s redirects to %s", errRedirect, from.Redacted(), to.Redacted())
+	}
+	return nil
+}
+
+// guardTransport denies requests to ports and paths outside the allowlist
+// before they reach next.
+type guardTransport struct {
+	next  http.RoundTripper
+	allow allowlist
+}
+
+func (t guardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow.checkURL(req.URL); err != nil {
+		log.Printf("Denied request %s %s: %s\n", req.Method, req.URL.Redacted(), err)
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// port returns the port of u, or the default port of its scheme.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// hasPathPrefix reports whether p is prefix or below it.
+func hasPathPrefix(p, prefix string) bool {
+	if prefix == "/" || p == prefix {
+		return true
+	}
+	return strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLoadAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{name: "defaults to the upstream ip and port", target: "http://10.0.0.5:8000/"},
+		{name: "ranges ports and paths", target: "http://10.0.0.5:8000/api", vars: map[string]string{"ALLOWED_UPSTREAM_CIDRS": "10.0.0.0/28, 10.0.1.0/28", "ALLOWED_UPSTREAM_PORTS": "80,8000", "ALLOWED_PATH_PREFIXES": "/api"}},
+		{name: "host name with ranges", target: "https://svc.internal/", vars: map[string]string{"ALLOWED_UPSTREAM_CIDRS": "10.0.0.0/28"}},
+		{name: "host name without ranges", target: "https://svc.internal/", wantErr: true},
+		{name: "invalid range", target: "http://10.0.0.5:8000/", vars: map[string]string{"ALLOWED_UPSTREAM_CIDRS": "10.0.0.5"}, wantErr: true},
+		{name: "invalid port", target: "http://10.0.0.5:8000/", vars: map[string]string{"ALLOWED_UPSTREAM_PORTS": "http"}, wantErr: true},
+		{name: "relative path prefix", target: "http://10.0.0.5:8000/", vars: map[string]string{"ALLOWED_PATH_PREFIXES": "api"}, wantErr: true},
+		{name: "upstream outside the ranges", target: "http://10.0.0.5:8000/", vars: map[string]string{"ALLOWED_UPSTREAM_CIDRS": "10.0.1.0/28"}, wantErr: true},
+		{name: "upstream port not allowed", target: "http://10.0.0.5:8000/", vars: map[string]string{"ALLOWED_UPSTREAM_PORTS": "80"}, wantErr: true},
+		{name: "upstream path not allowed", target: "http://10.0.0.5:8000/admin", vars: map[string]string{"ALLOWED_PATH_PREFIXES": "/api"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := url.Parse(tt.target)
+			if err != nil {
+				t.Fatalf("url.Parse(%q) error = %v", tt.target, err)
+			}
+			_, err = loadAllowlist(env(tt.vars), upstream{base: base})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadAllowlist() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	a := allowlist{
+		prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/28")},
+		ports:    []int{8000},
+		paths:    []string{"/api"},
+	}
+	tests := []struct {
+		url        string
+		wantDenied bool
+	}{
+		{url: "http://10.0.0.5:8000/api"},
+		{url: "http://10.0.0.5:8000/api/users/1?a=1"},
+		{url: "http://10.0.0.5:22/api", wantDenied: true},
+		{url: "http://10.0.0.5/api", wantDenied: true},
+		{url: "http://10.0.0.5:8000/", wantDenied: true},
+		{url: "http://10.0.0.5:8000/admin", wantDenied: true},
+		{url: "http://10.0.0.5:8000/apix", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api/../admin", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api/./users", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api/..", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api/%2e%2e/admin", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api/%2E%2E%2Fadmin", wantDenied: true},
+		{url: "http://10.0.0.5:8000/api%2F..%2Fadmin", wantDenied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("url.Parse(%q) error = %v", tt.url, err)
+			}
+			err = a.checkURL(u)
+			if tt.wantDenied != errors.Is(err, errDenied) {
+				t.Errorf("checkURL(%q) error = %v, want denied %t", tt.url, err, tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	a := allowlist{
+		prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/28"), netip.MustParsePrefix("fd00::/64")},
+		ports:    []int{8000},
+	}
+	tests := []struct {
+		address    string
+		wantDenied bool
+	}{
+		{address: "10.0.0.5:8000"},
+		{address: "10.0.0.15:8000"},
+		{address: "[::ffff:10.0.0.5]:8000"},
+		{address: "[fd00::1]:8000"},
+		{address: "10.0.0.16:8000", wantDenied: true},
+		{address: "169.254.169.254:80", wantDenied: true},
+		{address: "127.0.0.1:8000", wantDenied: true},
+		{address: "10.0.0.5:22", wantDenied: true},
+		{address: "svc.internal:8000", wantDenied: true},
+	}
+	for _, tt := range tests {
+		if err := a.checkAddr(tt.address); tt.wantDenied != errors.Is(err, errDenied) {
+			t.Errorf("checkAddr(%q) error = %v, want denied %t", tt.address, err, tt.wantDenied)
+		}
+	}
+}
+
+// TestControlChecksResolvedAddress dials a host name that resolves to the
+// loopback address, which the allowlist only allows in the second case.
+func TestControlChecksResolvedAddress(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	_, p, err := net.SplitHostPort(up.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		cidrs      string
+		wantDenied bool
+	}{
+		{cidrs: "10.0.0.0/28", wantDenied: true},
+		{cidrs: "127.0.0.0/8"},
+	} {
+		a, err := loadAllowlist(env(map[string]string{"ALLOWED_UPSTREAM_CIDRS": tt.cidrs, "ALLOWED_UPSTREAM_PORTS": p}), upstream{base: &url.URL{Scheme: "http", Host: "localhost:" + p}})
+		if err != nil {
+			t.Fatalf("loadAllowlist() error = %v", err)
+		}
+		d := net.Dialer{Timeout: time.Second, Control: a.control}
+		conn, err := d.Dial("tcp4", "localhost:"+p)
+		if err == nil {
+			conn.Close()
+		}
+		if tt.wantDenied != errors.Is(err, errDenied) {
+			t.Errorf("dial localhost with %s allowed: error = %v, want denied %t", tt.cidrs, err, tt.wantDenied)
+		}
+	}
+}
+
+func TestDeniedRequests(t *testing.T) {
+	called := false
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer up.Close()
+	_, p, _ := net.SplitHostPort(up.Listener.Addr().String())
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		path string
+	}{
+		{
+			name: "host name resolving outside the ranges",
+			vars: map[string]string{"TARGET_URL": "http://localhost:" + p + "/api", "ALLOWED_UPSTREAM_CIDRS": "10.0.0.0/28"},
+			path: "/",
+		},
+		{
+			name: "dot segments",
+			vars: map[string]string{"TARGET_URL": up.URL + "/api", "ALLOWED_PATH_PREFIXES": "/api"},
+			path: "/../admin",
+		},
+		{
+			name: "encoded dot segments",
+			vars: map[string]string{"TARGET_URL": up.URL + "/api", "ALLOWED_PATH_PREFIXES": "/api"},
+			path: "/%2e%2e%2fadmin",
+		},
+	}
+	for _, tt := range tests {
+		for _, proxyMode := range []string{"false", "true"} {
+			t.Run(tt.name+" proxy mode "+proxyMode, func(t *testing.T) {
+				called = false
+				vars := map[string]string{"FORWARD_REQUEST_PATH": "true", "PROXY_MODE": proxyMode}
+				for k, v := range tt.vars {
+					vars[k] = v
+				}
+				s, err := newServer(env(vars))
+				if err != nil {
+					t.Fatalf("newServer() error = %v", err)
+				}
+				rec := httptest.NewRecorder()
+				s.handler()(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if rec.Code != http.StatusForbidden || called {
+					t.Errorf("status = %d and upstream called %t, want %d without calling the upstream", rec.Code, called, http.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	from, _ := url.Parse("http://10.0.0.5:8000/api/users")
+	tests := []struct {
+		location string
+		wantErr  bool
+	}{
+		{location: "/api/login"},
+		{location: "login"},
+		{location: "http://10.0.0.5:8000/api/login"},
+		{location: "//10.0.0.5:8000/api/login"},
+		{location: "http://10.0.0.6:8000/api/login", wantErr: true},
+		{location: "http://10.0.0.5:8080/api/login", wantErr: true},
+		{location: "https://10.0.0.5:8000/api/login", wantErr: true},
+		{location: "//attacker.example/", wantErr: true},
+		{location: "http://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := checkRedirect(from, tt.location)
+		if tt.wantErr != errors.Is(err, errRedirect) {
+			t.Errorf("checkRedirect(%q) error = %v, want refused %t", tt.location, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDeniedTrialReleasesBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := &fakeTransport{status: http.StatusServiceUnavailable}
+	rt := &resilientTransport{next: next, breaker: &breaker{threshold: 1, cooldown: time.Minute, now: func() time.Time { return now }}}
+	req := httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil)
+	rt.RoundTrip(req)
+
+	// The trial request after the cooldown is denied by the allowlist.
+	now = now.Add(time.Minute)
+	next.status, next.err = 0, errDenied
+	if _, err := rt.RoundTrip(req); !errors.Is(err, errDenied) {
+		t.Fatalf("RoundTrip() error = %v, want %v", err, errDenied)
+	}
+
+	next.status, next.err = http.StatusOK, nil
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("RoundTrip() after a denied trial = %v, %v, want 200", resp, err)
+	}
+}
@@ -15,43 +15,127 @@
 package helloworld
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
 	"os"
+	"time"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 )
 
+// server calls the internal server on behalf of the function.
+type server struct {
+	// target is the internal server, validated once at cold start.
+	target upstream
+	// client calls target with timeouts, retries and a circuit breaker.
+	client *http.Client
+	// proxy forwards requests to target in proxy mode, and is nil otherwise.
+	proxy *httputil.ReverseProxy
+	// timeout bounds every request to the function. Zero disables it.
+	timeout time.Duration
+}
+
 func init() {
-	functions.HTTP("helloHTTP", helloHTTP)
+	s, err := newServer(os.Getenv)
+	if err != nil {
+		// Not fatal, so that the tests of this package can load it without the
+		// environment of the function. Every request fails until it is fixed.
+		log.Printf("Invalid configuration, every request will fail: %s", err)
+		functions.HTTP("helloHTTP", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Invalid function configuration", http.StatusInternalServerError)
+		})
+		return
+	}
+	functions.HTTP("helloHTTP", s.handler())
 }
 
-func helloHTTP(w http.ResponseWriter, r *http.Request) {
-	ipAddress := os.Getenv("TARGET_IP")
-	if ipAddress == "" {
-		log.Println("TARGET_IP environment variable not set")
-		http.Error(w, "TARGET_IP not set", http.StatusInternalServerError)
+// newServer returns a server configured by getenv.
+func newServer(getenv func(string) string) (*server, error) {
+	target, err := loadUpstream(getenv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream configuration: %w", err)
+	}
+	allow, err := loadAllowlist(getenv, target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowlist configuration: %w", err)
+	}
+	res, err := loadResilience(getenv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resilience configuration: %w", err)
+	}
+	tlsConfig, err := loadTLS(getenv, target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	transport := newTransport(res, allow, tlsConfig)
+	proxy, err := loadProxy(getenv, target, transport)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy configuration: %w", err)
+	}
+	log.Printf("Forwarding requests to %s (forward request path: %t, proxy mode: %t, TLS files: %t)", target.base.Redacted(), target.forwardPath, proxy != nil, tlsConfig != nil)
+	return &server{target: target, client: newClient(transport), proxy: proxy, timeout: res.timeout}, nil
+}
+
+// newClient returns the client of the calls to the internal server outside
+// proxy mode. It follows redirects to the same host only.
+func newClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if err := checkRedirect(via[len(via)-1].URL, req.URL.String()); err != nil {
+				log.Printf("Refused redirect: %s\n", err)
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// handler returns the function handler.
+func (s *server) handler() http.HandlerFunc {
+	return withTimeout(s.helloHTTP, s.timeout)
+}
+
+func (s *server) helloHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.proxy != nil {
+		s.proxy.ServeHTTP(w, r)
 		return
 	}
 
-	url := fmt.Sprintf("http://%s:8000/index.html", ipAddress)
+	url := s.target.target(r.URL.Path, r.URL.RawQuery).String()
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("Failed to create GET request: %s\n", err)
+		http.Error(w, "Failed to create GET request", http.StatusInternalServerError)
+		return
+	}
 
 	// Send GET request to the server
-	response, err := http.Get(url)
+	response, err := s.client.Do(request)
 	if err != nil {
 		log.Printf("Failed to send GET request: %s\n", err)
-		http.Error(w, "Failed to send GET request", http.StatusInternalServerError)
+		writeUpstreamError(w, err)
 		return
 	}
 	defer response.Body.Close()
+	if response.StatusCode >= http.StatusInternalServerError {
+		log.Printf("Internal server returned %s\n", response.Status)
+		http.Error(w, fmt.Sprintf("Internal server returned %s", response.Status), http.StatusBadGateway)
+		return
+	}
 
 	// Read the response body
-	content, err := ioutil.ReadAll(response.Body)
+	content, err := io.ReadAll(response.Body)
 	if err != nil {
 		log.Printf("Failed to read response body: %s\n", err)
-		http.Error(w, "Failed to read response body", http.StatusInternalServerError)
+		writeUpstreamError(w, err)
 		return
 	}
 
@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+)
+
+// defaultForwardHeaders are the request headers forwarded to the internal server
+// in proxy mode. Authorization is never forwarded: it carries the identity token
+// of the function invoker, not a credential for the internal server.
+var defaultForwardHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Cache-Control",
+	"Content-Encoding",
+	"Content-Type",
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Unmodified-Since",
+	"Range",
+	"User-Agent",
+	"X-Request-Id",
+}
+
+// loadProxy returns a reverse proxy to u when PROXY_MODE is true, or nil otherwise.
+// PROXY_FORWARD_HEADERS replaces the default list of forwarded request headers.
+func loadProxy(getenv func(string) string, u upstream, transport http.RoundTripper) (*httputil.ReverseProxy, error) {
+	enabled, err := boolEnv(getenv, "PROXY_MODE")
+	if err != nil || !enabled {
+		return nil, err
+	}
+	headers := defaultForwardHeaders
+	if v := splitList(getenv("PROXY_FORWARD_HEADERS")); len(v) > 0 {
+		headers = v
+	}
+	return newProxy(u, headers, transport), nil
+}
+
+// newProxy returns a reverse proxy that forwards the method, body and the given
+// headers of each request to u, and streams back the status code, headers and
+// body of the response.
+func newProxy(u upstream, headers []string, transport http.RoundTripper) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL = u.target(pr.In.URL.Path, pr.In.URL.RawQuery)
+			pr.Out.Host = pr.Out.URL.Host
+			pr.Out.Header = filterHeader(pr.Out.Header, headers)
+			pr.SetXForwarded()
+		},
+		// Refuse redirects to other hosts rather than handing them to the client.
+		ModifyResponse: func(resp *http.Response) error {
+			if location := resp.Header.Get("Location"); location != "" && isRedirect(resp.StatusCode) {
+				return checkRedirect(resp.Request.URL, location)
+			}
+			return nil
+		},
+		// Flush every write so that streamed responses reach the client as they arrive.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Failed to proxy %s %s: %s\n", r.Method, r.URL.Path, err)
+			writeUpstreamError(w, err)
+		},
+	}
+}
+
+func isRedirect(code int) bool {
+	return code >= http.StatusMultipleChoices && code < http.StatusBadRequest
+}
+
+// filterHeader returns the values of h for the given header names only.
+func filterHeader(h http.Header, names []string) http.Header {
+	out := make(http.Header, len(names))
+	for _, name := range names {
+		if v := h.Values(name); len(v) > 0 {
+			out[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	return out
+}
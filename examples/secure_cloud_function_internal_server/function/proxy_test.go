@@ -0,0 +1,184 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newProxyServer returns a server in proxy mode to an upstream serving h under /api,
+// without retries or circuit breaker unless vars sets them.
+func newProxyServer(t *testing.T, h http.HandlerFunc, vars map[string]string) *server {
+	t.Helper()
+	up := httptest.NewServer(h)
+	t.Cleanup(up.Close)
+	all := map[string]string{
+		"TARGET_URL":                up.URL + "/api",
+		"FORWARD_REQUEST_PATH":      "true",
+		"PROXY_MODE":                "true",
+		"UPSTREAM_RETRIES":          "0",
+		"CIRCUIT_BREAKER_THRESHOLD": "0",
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+	s, err := newServer(env(all))
+	if err != nil {
+		t.Fatalf("newServer() error = %v", err)
+	}
+	return s
+}
+
+func TestProxyForwardsRequest(t *testing.T) {
+	var got struct {
+		method, uri, body string
+		header            http.Header
+	}
+	s := newProxyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got.method, got.uri, got.body, got.header = r.Method, r.RequestURI, string(body), r.Header
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "internal")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users?team=a&team=b", strings.NewReader(`{"name":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "42")
+	rec := httptest.NewRecorder()
+	s.handler()(rec, req)
+
+	if got.method != http.MethodPost || got.uri != "/api/users?team=a&team=b" || got.body != `{"name":"a"}` {
+		t.Errorf("upstream got %s %s %q, want POST /api/users?team=a&team=b %q", got.method, got.uri, got.body, `{"name":"a"}`)
+	}
+	for name, want := range map[string]string{"Content-Type": "application/json", "X-Request-Id": "42"} {
+		if v := got.header.Get(name); v != want {
+			t.Errorf("upstream got %s %q, want %q", name, v, want)
+		}
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"id":1}` {
+		t.Errorf("response = %d %q, want 201 %q", rec.Code, rec.Body, `{"id":1}`)
+	}
+	if v := rec.Header().Get("X-Upstream"); v != "internal" {
+		t.Errorf("response X-Upstream = %q, want %q", v, "internal")
+	}
+}
+
+func TestProxyStripsHeaders(t *testing.T) {
+	var header http.Header
+	s := newProxyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, v := range map[string]string{
+		"Authorization":       "Bearer invoker-token",
+		"Cookie":              "session=1",
+		"Proxy-Authorization": "Basic dXNlcjpwYXNz",
+		"Connection":          "X-Hop",
+		"X-Hop":               "1",
+		"Keep-Alive":          "timeout=5",
+		"Te":                  "trailers",
+		"Upgrade":             "websocket",
+		"X-Custom":            "1",
+		"Accept":              "text/plain",
+	} {
+		req.Header.Set(name, v)
+	}
+	s.handler()(httptest.NewRecorder(), req)
+
+	for _, name := range []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Hop", "Keep-Alive", "Te", "Upgrade", "X-Custom"} {
+		if v := header.Get(name); v != "" {
+			t.Errorf("upstream got %s %q, want it stripped", name, v)
+		}
+	}
+	if v := header.Get("Accept"); v != "text/plain" {
+		t.Errorf("upstream got Accept %q, want %q", v, "text/plain")
+	}
+}
+
+func TestProxyForwardHeadersOverride(t *testing.T) {
+	var header http.Header
+	s := newProxyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}, map[string]string{"PROXY_FORWARD_HEADERS": "X-Custom"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Custom", "1")
+	req.Header.Set("Accept", "text/plain")
+	s.handler()(httptest.NewRecorder(), req)
+
+	if header.Get("X-Custom") != "1" || header.Get("Accept") != "" {
+		t.Errorf("upstream got X-Custom %q and Accept %q, want only X-Custom", header.Get("X-Custom"), header.Get("Accept"))
+	}
+}
+
+func TestProxyStatusPassthrough(t *testing.T) {
+	for _, code := range []int{http.StatusNoContent, http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable} {
+		s := newProxyServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}, nil)
+		rec := httptest.NewRecorder()
+		s.handler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != code {
+			t.Errorf("status = %d, want %d", rec.Code, code)
+		}
+	}
+}
+
+func TestProxyRedirects(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		want     int
+	}{
+		{name: "same host", location: "/api/other", want: http.StatusFound},
+		{name: "other host", location: "http://attacker.example/", want: http.StatusBadGateway},
+		{name: "other scheme", location: "https://127.0.0.1/", want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newProxyServer(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, tt.location, http.StatusFound)
+			}, nil)
+			rec := httptest.NewRecorder()
+			s.handler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRefusesRedirects(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://attacker.example/", http.StatusFound)
+	}))
+	defer up.Close()
+	s, err := newServer(env(map[string]string{"TARGET_URL": up.URL + "/index.html"}))
+	if err != nil {
+		t.Fatalf("newServer() error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.handler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+}
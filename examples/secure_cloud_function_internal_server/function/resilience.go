@@ -0,0 +1,281 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned without calling the internal server while the circuit breaker is open.
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// resilience configures how the function calls the internal server.
+type resilience struct {
+	// connectTimeout bounds establishing a connection, including the TLS handshake.
+	// Zero disables it.
+	connectTimeout time.Duration
+	// timeout bounds a whole request to the function, including retries. Zero disables it.
+	timeout time.Duration
+	// retries is how many times a failed idempotent request is retried.
+	retries int
+	// backoff is the base delay between retries. The delay of retry n is
+	// a random duration up to backoff * 2^n.
+	backoff time.Duration
+	// threshold is how many consecutive failures open the circuit breaker. Zero disables it.
+	threshold int
+	// cooldown is how long the circuit breaker stays open before a trial request.
+	cooldown time.Duration
+}
+
+// loadResilience reads the timeouts, retries and circuit breaker settings from the environment.
+func loadResilience(getenv func(string) string) (resilience, error) {
+	var (
+		c   resilience
+		err error
+	)
+	if c.connectTimeout, err = durationEnv(getenv, "UPSTREAM_CONNECT_TIMEOUT", 5*time.Second); err != nil {
+		return c, err
+	}
+	if c.timeout, err = durationEnv(getenv, "UPSTREAM_TIMEOUT", 30*time.Second); err != nil {
+		return c, err
+	}
+	if c.retries, err = intEnv(getenv, "UPSTREAM_RETRIES", 2); err != nil {
+		return c, err
+	}
+	if c.backoff, err = durationEnv(getenv, "UPSTREAM_RETRY_BACKOFF", 100*time.Millisecond); err != nil {
+		return c, err
+	}
+	if c.threshold, err = intEnv(getenv, "CIRCUIT_BREAKER_THRESHOLD", 5); err != nil {
+		return c, err
+	}
+	if c.cooldown, err = durationEnv(getenv, "CIRCUIT_BREAKER_COOLDOWN", 30*time.Second); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// resilientTransport retries idempotent requests with jittered backoff and
+// fails fast while its circuit breaker is open.
+type resilientTransport struct {
+	next    http.RoundTripper
+	retries int
+	backoff time.Duration
+	breaker *breaker
+}
+
+// newTransport returns the transport of every call to the internal server.
+// It connects directly, without the proxy of the environment, and only to
+// addresses and ports of allow. A nil tlsConfig uses the default TLS configuration.
+func newTransport(c resilience, allow allowlist, tlsConfig *tls.Config) http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = nil
+	base.TLSClientConfig = tlsConfig
+	base.DialContext = (&net.Dialer{Timeout: c.connectTimeout, KeepAlive: 30 * time.Second, Control: allow.control}).DialContext
+	base.TLSHandshakeTimeout = c.connectTimeout
+	return guardTransport{
+		allow: allow,
+		next: &resilientTransport{
+			next:    base,
+			retries: c.retries,
+			backoff: c.backoff,
+			breaker: &breaker{threshold: c.threshold, cooldown: c.cooldown, now: time.Now},
+		},
+	}
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+	resp, err := t.roundTrip(req)
+	switch {
+	case errors.Is(err, errDenied), errors.Is(err, errRedirect), errors.Is(err, context.Canceled):
+		// A denied connection, a refused redirect or a client that went away
+		// says nothing about the health of the internal server.
+		t.breaker.release()
+	default:
+		t.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	}
+	return resp, err
+}
+
+func (t *resilientTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	retries := t.retries
+	if !retryable(req) {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.next.RoundTrip(r)
+		if attempt == retries || req.Context().Err() != nil || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.Printf("Retrying %s %s after attempt %d failed: %s\n", req.Method, req.URL.Redacted(), attempt+1, failure(resp, err))
+		select {
+		case <-time.After(jitter(t.backoff, attempt)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryable reports whether req is idempotent and its body can be sent again.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+// shouldRetry reports whether a failed attempt may succeed when retried.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, errDenied)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func failure(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+// jitter returns a random delay up to base * 2^attempt.
+func jitter(base time.Duration, attempt int) time.Duration {
+	max := base << attempt
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// breaker is a circuit breaker. It opens after threshold consecutive failures,
+// rejects every request for cooldown, and then lets a single trial request
+// through: the circuit closes when it succeeds and opens again when it fails.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// allow reports whether a request may be sent.
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record updates the breaker with the outcome of an allowed request.
+func (b *breaker) record(ok bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			log.Printf("Opening the circuit breaker for %s after %d consecutive failures\n", b.cooldown, b.failures)
+		}
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// release ends an allowed request without recording its outcome, so that a
+// trial request denied by the allowlist doesn't leave the breaker half-open.
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// writeUpstreamError answers a request whose call to the internal server failed:
+// 403 when the allowlist denied it, 504 when it timed out and 502 otherwise.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errDenied):
+		http.Error(w, "Upstream not allowed", http.StatusForbidden)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		http.Error(w, "Timed out waiting for the internal server", http.StatusGatewayTimeout)
+	case errors.Is(err, errCircuitOpen):
+		http.Error(w, "Internal server is unavailable", http.StatusBadGateway)
+	default:
+		http.Error(w, "Failed to reach the internal server", http.StatusBadGateway)
+	}
+}
+
+// withTimeout bounds every request handled by h to timeout, unless it is zero.
+func withTimeout(h http.HandlerFunc, timeout time.Duration) http.HandlerFunc {
+	if timeout == 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		h(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTransport answers every request with status, or with err when set, and
+// records the body of each attempt.
+type fakeTransport struct {
+	status int
+	err    error
+	bodies []string
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	t.bodies = append(t.bodies, string(body))
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{StatusCode: t.status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          func() *http.Request
+		status       int
+		err          error
+		wantAttempts int
+	}{
+		{
+			name:         "get retried up to the bound",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 3,
+		},
+		{
+			name:         "connection error retried",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			err:          errors.New("connection refused"),
+			wantAttempts: 3,
+		},
+		{
+			name:         "success not retried",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			status:       http.StatusOK,
+			wantAttempts: 1,
+		},
+		{
+			name:         "client error not retried",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			status:       http.StatusNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:         "internal server error not retried",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			status:       http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "denied not retried",
+			req:          func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil) },
+			err:          fmt.Errorf("%w: address 10.0.0.6", errDenied),
+			wantAttempts: 1,
+		},
+		{
+			name: "post not retried",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodPost, "http://10.0.0.5/", strings.NewReader("body"))
+				return r
+			},
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		{
+			name: "consumed body not retried",
+			req: func() *http.Request {
+				r, _ := http.NewRequest(http.MethodPut, "http://10.0.0.5/", io.NopCloser(strings.NewReader("body")))
+				return r
+			},
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeTransport{status: tt.status, err: tt.err}
+			rt := &resilientTransport{next: next, retries: 2, breaker: &breaker{}}
+			resp, err := rt.RoundTrip(tt.req())
+			if len(next.bodies) != tt.wantAttempts {
+				t.Errorf("RoundTrip() made %d attempts, want %d", len(next.bodies), tt.wantAttempts)
+			}
+			if (err != nil) != (tt.err != nil) {
+				t.Errorf("RoundTrip() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && resp.StatusCode != tt.status {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestRetryResendsBody(t *testing.T) {
+	next := &fakeTransport{status: http.StatusServiceUnavailable}
+	rt := &resilientTransport{next: next, retries: 2, breaker: &breaker{}}
+	req, _ := http.NewRequest(http.MethodPut, "http://10.0.0.5/", strings.NewReader("body"))
+	rt.RoundTrip(req)
+	if want := []string{"body", "body", "body"}; strings.Join(next.bodies, ",") != strings.Join(want, ",") {
+		t.Errorf("attempts sent bodies %q, want %q", next.bodies, want)
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	next := &fakeTransport{status: http.StatusServiceUnavailable}
+	rt := &resilientTransport{next: next, retries: 5, backoff: time.Hour, breaker: &breaker{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://10.0.0.5/", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(next.bodies) != 1 {
+		t.Errorf("RoundTrip() made %d attempts, want 1", len(next.bodies))
+	}
+}
+
+// canceledTransport blocks until the request is canceled.
+type canceledTransport struct{}
+
+func (canceledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestCanceledRequestNotCounted(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Minute, now: time.Now}
+	rt := &resilientTransport{next: canceledTransport{}, retries: 2, breaker: b}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://10.0.0.5/", nil)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RoundTrip() error = %v, want %v", err, context.Canceled)
+	}
+	if !b.allow() {
+		t.Error("breaker open after a canceled request, want closed")
+	}
+}
+
+func TestRefusedRedirectNotCounted(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Minute, now: time.Now}
+	rt := &resilientTransport{next: &fakeTransport{err: fmt.Errorf("proxy: %w", errRedirect)}, breaker: b}
+	if _, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil)); !errors.Is(err, errRedirect) {
+		t.Fatalf("RoundTrip() error = %v, want %v", err, errRedirect)
+	}
+	if !b.allow() {
+		t.Error("breaker open after a refused redirect, want closed")
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := &breaker{threshold: 2, cooldown: 30 * time.Second, now: func() time.Time { return now }}
+
+	// Closed: failures below the threshold don't open it, and a success resets them.
+	b.record(false)
+	b.record(true)
+	b.record(false)
+	if !b.allow() {
+		t.Fatal("breaker open after 1 consecutive failure, want closed")
+	}
+	b.record(false)
+
+	// Open: every request is rejected until the cooldown ends.
+	if b.allow() {
+		t.Fatal("breaker closed after 2 consecutive failures, want open")
+	}
+	now = now.Add(29 * time.Second)
+	if b.allow() {
+		t.Fatal("breaker closed before the cooldown ended, want open")
+	}
+
+	// Half-open: a single trial request is let through, and its failure opens it again.
+	now = now.Add(time.Second)
+	if !b.allow() {
+		t.Fatal("breaker rejected the trial request after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("breaker let a second request through while half-open")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Fatal("breaker closed after the trial request failed, want open")
+	}
+
+	// A successful trial request closes it.
+	now = now.Add(30 * time.Second)
+	if !b.allow() {
+		t.Fatal("breaker rejected the trial request after the cooldown")
+	}
+	b.record(true)
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatal("breaker open after the trial request succeeded, want closed")
+		}
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := &breaker{now: time.Now}
+	for i := 0; i < 10; i++ {
+		b.record(false)
+	}
+	if !b.allow() {
+		t.Error("disabled breaker rejected a request")
+	}
+}
+
+func TestCircuitOpenSkipsUpstream(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := &fakeTransport{status: http.StatusBadGateway}
+	rt := &resilientTransport{next: next, breaker: &breaker{threshold: 1, cooldown: time.Minute, now: func() time.Time { return now }}}
+	req := httptest.NewRequest(http.MethodGet, "http://10.0.0.5/", nil)
+	rt.RoundTrip(req)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, errCircuitOpen)
+	}
+	if len(next.bodies) != 1 {
+		t.Errorf("RoundTrip() made %d attempts, want 1", len(next.bodies))
+	}
+}
+
+func TestWriteUpstreamError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: fmt.Errorf("dial: %w", errDenied), want: http.StatusForbidden},
+		{err: fmt.Errorf("request: %w", context.DeadlineExceeded), want: http.StatusGatewayTimeout},
+		{err: &net.DNSError{Err: "timeout", IsTimeout: true}, want: http.StatusGatewayTimeout},
+		{err: errCircuitOpen, want: http.StatusBadGateway},
+		{err: fmt.Errorf("proxy: %w", errRedirect), want: http.StatusBadGateway},
+		{err: errors.New("connection refused"), want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeUpstreamError(rec, tt.err)
+		if rec.Code != tt.want {
+			t.Errorf("writeUpstreamError(%v) status = %d, want %d", tt.err, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestUpstreamErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		h    http.HandlerFunc
+		want int
+	}{
+		{
+			name: "server error",
+			h:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			want: http.StatusBadGateway,
+		},
+		{
+			name: "timeout",
+			vars: map[string]string{"UPSTREAM_TIMEOUT": "20ms"},
+			h: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			want: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := httptest.NewServer(tt.h)
+			defer up.Close()
+			vars := map[string]string{"TARGET_URL": up.URL + "/index.html", "UPSTREAM_RETRIES": "0"}
+			for k, v := range tt.vars {
+				vars[k] = v
+			}
+			s, err := newServer(env(vars))
+			if err != nil {
+				t.Fatalf("newServer() error = %v", err)
+			}
+			rec := httptest.NewRecorder()
+			s.handler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// mtls holds the client certificate and CA bundle the function presents to and
+// verifies the internal server with. They are read from files, usually secret
+// volumes, and reloaded when the mounted secret versions change.
+type mtls struct {
+	certFile, keyFile, caFile string
+	// serverName is the name the certificate of the internal server must have:
+	// TLS_SERVER_NAME, or else the upstream host, which may be an IP address.
+	serverName string
+	// interval is how often the files are checked for changes.
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	checked time.Time
+	certPEM []byte
+	keyPEM  []byte
+	caPEM   []byte
+	cert    *tls.Certificate
+	roots   *x509.CertPool
+}
+
+// loadTLS returns the TLS configuration of calls to u, or nil when none of
+// TLS_CLIENT_CERT_FILE, TLS_CLIENT_KEY_FILE and TLS_CA_FILE is set. Without
+// TLS_CA_FILE the server is verified against the system roots.
+func loadTLS(getenv func(string) string, u upstream) (*tls.Config, error) {
+	m := &mtls{
+		certFile:   getenv("TLS_CLIENT_CERT_FILE"),
+		keyFile:    getenv("TLS_CLIENT_KEY_FILE"),
+		caFile:     getenv("TLS_CA_FILE"),
+		serverName: orDefault(getenv("TLS_SERVER_NAME"), u.base.Hostname()),
+		now:        time.Now,
+	}
+	if m.certFile == "" && m.keyFile == "" && m.caFile == "" {
+		return nil, nil
+	}
+	if (m.certFile == "") != (m.keyFile == "") {
+		return nil, errors.New("TLS_CLIENT_CERT_FILE and TLS_CLIENT_KEY_FILE must be set together")
+	}
+	if u.base.Scheme != "https" {
+		return nil, fmt.Errorf("TLS files are set but the upstream %s does not use https", u.base.Redacted())
+	}
+	var err error
+	if m.interval, err = durationEnv(getenv, "TLS_RELOAD_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           m.serverName,
+		GetClientCertificate: m.clientCertificate,
+		// The server certificate is verified by VerifyConnection against the
+		// current CA bundle, which changes when the mounted secret is rotated.
+		InsecureSkipVerify: true,
+		VerifyConnection:   m.verify,
+	}, nil
+}
+
+// load reads and parses the files, and keeps them only if they are all valid.
+func (m *mtls) load() error {
+	var certPEM, keyPEM, caPEM []byte
+	var err error
+	if m.certFile != "" {
+		if certPEM, err = os.ReadFile(m.certFile); err != nil {
+			return err
+		}
+		if keyPEM, err = os.ReadFile(m.keyFile); err != nil {
+			return err
+		}
+	}
+	if m.caFile != "" {
+		if caPEM, err = os.ReadFile(m.caFile); err != nil {
+			return err
+		}
+	}
+	// Empty files read as the initial nil contents, so they are only skipped
+	// once the files have been loaded.
+	unchanged := !m.checked.IsZero() && bytes.Equal(certPEM, m.certPEM) && bytes.Equal(keyPEM, m.keyPEM) && bytes.Equal(caPEM, m.caPEM)
+	m.checked = m.now()
+	if unchanged {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if certPEM != nil {
+		c, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate %s: %v", m.certFile, err)
+		}
+		cert = &c
+	}
+	var roots *x509.CertPool
+	if caPEM != nil {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates in CA bundle %s", m.caFile)
+		}
+	}
+	if m.cert != nil || m.roots != nil {
+		log.Println("Reloaded the TLS client certificate and CA bundle")
+	}
+	m.certPEM, m.keyPEM, m.caPEM = certPEM, keyPEM, caPEM
+	m.cert, m.roots = cert, roots
+	return nil
+}
+
+// current returns the client certificate and CA bundle, reloading the files at
+// most once per interval. A failed reload keeps the previous ones, so that a
+// rotation observed half way doesn't break the calls.
+func (m *mtls) current() (*tls.Certificate, *x509.CertPool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.now().Sub(m.checked) >= m.interval {
+		if err := m.load(); err != nil {
+			log.Printf("Failed to reload the TLS files, keeping the previous ones: %s\n", err)
+		}
+	}
+	return m.cert, m.roots
+}
+
+func (m *mtls) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := m.current()
+	if cert == nil {
+		// No client certificate is configured: send none.
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
+
+// verify checks the certificate chain of the server against the CA bundle, or
+// the system roots when there is none, and its name against serverName. The
+// server name of the connection can't be used: it is empty for IP addresses.
+func (m *mtls) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("the server presented no certificate")
+	}
+	_, roots := m.current()
+	opts := x509.VerifyOptions{
+		DNSName:       m.serverName,
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
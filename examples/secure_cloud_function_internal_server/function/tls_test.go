@@ -0,0 +1,315 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helloworld
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing the certificates of the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for tmpl signed by the CA, and its key, in PEM.
+func (c testCA) issue(t *testing.T, tmpl *x509.Certificate) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (c testCA) client(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	return c.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: name}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+}
+
+// writeFile writes data to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMTLS(t *testing.T) {
+	ca, otherCA := newTestCA(t), newTestCA(t)
+	clientCert, clientKey := ca.client(t, "function")
+	dir := t.TempDir()
+	files := map[string]string{
+		"TLS_CLIENT_CERT_FILE": writeFile(t, dir, "tls.crt", clientCert),
+		"TLS_CLIENT_KEY_FILE":  writeFile(t, dir, "tls.key", clientKey),
+		"TLS_CA_FILE":          writeFile(t, dir, "ca.crt", ca.pem),
+	}
+	loopback := net.ParseIP("127.0.0.1")
+
+	tests := []struct {
+		name       string
+		serverCA   testCA
+		serverCert *x509.Certificate
+		serverName string
+		want       int
+	}{
+		{
+			name:       "ip address in the certificate",
+			serverCA:   ca,
+			serverCert: &x509.Certificate{IPAddresses: []net.IP{loopback}},
+			want:       http.StatusOK,
+		},
+		{
+			name:       "other ip address in the certificate",
+			serverCA:   ca,
+			serverCert: &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.0.0.9")}},
+			want:       http.StatusBadGateway,
+		},
+		{
+			name:       "server name in the certificate",
+			serverCA:   ca,
+			serverCert: &x509.Certificate{DNSNames: []string{"svc.internal"}},
+			serverName: "svc.internal",
+			want:       http.StatusOK,
+		},
+		{
+			name:       "other server name in the certificate",
+			serverCA:   ca,
+			serverCert: &x509.Certificate{DNSNames: []string{"other.internal"}, IPAddresses: []net.IP{loopback}},
+			serverName: "svc.internal",
+			want:       http.StatusBadGateway,
+		},
+		{
+			name:       "certificate of another CA",
+			serverCA:   otherCA,
+			serverCert: &x509.Certificate{IPAddresses: []net.IP{loopback}},
+			want:       http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.serverCert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+			certPEM, keyPEM := tt.serverCA.issue(t, tt.serverCert)
+			serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientCAs := x509.NewCertPool()
+			clientCAs.AppendCertsFromPEM(ca.pem)
+			up := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello " + r.TLS.PeerCertificates[0].Subject.CommonName))
+			}))
+			up.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+			up.StartTLS()
+			defer up.Close()
+
+			vars := map[string]string{"TARGET_URL": up.URL + "/", "TLS_SERVER_NAME": tt.serverName, "UPSTREAM_RETRIES": "0"}
+			for k, v := range files {
+				vars[k] = v
+			}
+			s, err := newServer(env(vars))
+			if err != nil {
+				t.Fatalf("newServer() error = %v", err)
+			}
+			rec := httptest.NewRecorder()
+			s.handler()(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d %q, want %d", rec.Code, rec.Body, tt.want)
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != "hello function" {
+				t.Errorf("body = %q, want %q", rec.Body, "hello function")
+			}
+		})
+	}
+}
+
+func TestMTLSReload(t *testing.T) {
+	ca, newCA := newTestCA(t), newTestCA(t)
+	cert1, key1 := ca.client(t, "function-1")
+	cert2, key2 := ca.client(t, "function-2")
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &mtls{
+		certFile: writeFile(t, dir, "tls.crt", cert1),
+		keyFile:  writeFile(t, dir, "tls.key", key1),
+		caFile:   writeFile(t, dir, "ca.crt", ca.pem),
+		interval: time.Minute,
+		now:      func() time.Time { return now },
+	}
+	if err := m.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	check := func(wantCert, wantCA []byte) {
+		t.Helper()
+		cert, roots := m.current()
+		if block, _ := pem.Decode(wantCert); !bytes.Equal(cert.Certificate[0], block.Bytes) {
+			t.Errorf("current() returned the certificate %s, want %s", leafName(t, cert), leafName(t, &tls.Certificate{Certificate: [][]byte{block.Bytes}}))
+		}
+		want := x509.NewCertPool()
+		want.AppendCertsFromPEM(wantCA)
+		if !roots.Equal(want) {
+			t.Error("current() returned another CA bundle")
+		}
+	}
+
+	// A rotation is picked up once the interval has passed.
+	writeFile(t, dir, "tls.crt", cert2)
+	writeFile(t, dir, "tls.key", key2)
+	now = now.Add(time.Minute - time.Second)
+	check(cert1, ca.pem)
+	now = now.Add(time.Second)
+	check(cert2, ca.pem)
+
+	// A certificate written before its key keeps the previous pair.
+	writeFile(t, dir, "tls.crt", cert1)
+	now = now.Add(time.Minute)
+	check(cert2, ca.pem)
+	writeFile(t, dir, "tls.key", key1)
+	now = now.Add(time.Minute)
+	check(cert1, ca.pem)
+
+	// An invalid or missing file keeps the previous ones.
+	writeFile(t, dir, "ca.crt", []byte("not a certificate"))
+	now = now.Add(time.Minute)
+	check(cert1, ca.pem)
+	if err := os.Remove(m.keyFile); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	check(cert1, ca.pem)
+
+	writeFile(t, dir, "tls.key", key1)
+	writeFile(t, dir, "ca.crt", newCA.pem)
+	now = now.Add(time.Minute)
+	check(cert1, newCA.pem)
+}
+
+func leafName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestLoadTLS(t *testing.T) {
+	ca := newTestCA(t)
+	cert1, key1 := ca.client(t, "function-1")
+	_, key2 := ca.client(t, "function-2")
+	dir := t.TempDir()
+	certFile := writeFile(t, dir, "tls.crt", cert1)
+	keyFile := writeFile(t, dir, "tls.key", key1)
+	otherKeyFile := writeFile(t, dir, "other.key", key2)
+	caFile := writeFile(t, dir, "ca.crt", ca.pem)
+	emptyFile := writeFile(t, dir, "empty.crt", nil)
+
+	tests := []struct {
+		name       string
+		vars       map[string]string
+		wantConfig bool
+		wantErr    bool
+	}{
+		{name: "no files", vars: map[string]string{}},
+		{name: "client certificate and CA", vars: map[string]string{"TLS_CLIENT_CERT_FILE": certFile, "TLS_CLIENT_KEY_FILE": keyFile, "TLS_CA_FILE": caFile}, wantConfig: true},
+		{name: "CA only", vars: map[string]string{"TLS_CA_FILE": caFile}, wantConfig: true},
+		{name: "client certificate only", vars: map[string]string{"TLS_CLIENT_CERT_FILE": certFile, "TLS_CLIENT_KEY_FILE": keyFile}, wantConfig: true},
+		{name: "certificate without key", vars: map[string]string{"TLS_CLIENT_CERT_FILE": certFile}, wantErr: true},
+		{name: "key without certificate", vars: map[string]string{"TLS_CLIENT_KEY_FILE": keyFile}, wantErr: true},
+		{name: "key of another certificate", vars: map[string]string{"TLS_CLIENT_CERT_FILE": certFile, "TLS_CLIENT_KEY_FILE": otherKeyFile}, wantErr: true},
+		{name: "certificate and key swapped", vars: map[string]string{"TLS_CLIENT_CERT_FILE": keyFile, "TLS_CLIENT_KEY_FILE": certFile}, wantErr: true},
+		{name: "missing file", vars: map[string]string{"TLS_CA_FILE": filepath.Join(dir, "missing.crt")}, wantErr: true},
+		{name: "empty CA bundle", vars: map[string]string{"TLS_CA_FILE": emptyFile}, wantErr: true},
+		{name: "invalid reload interval", vars: map[string]string{"TLS_CA_FILE": caFile, "TLS_RELOAD_INTERVAL": "often"}, wantErr: true},
+		{name: "http upstream", vars: map[string]string{"TARGET_URL": "http://10.0.0.5:8000/", "TLS_CA_FILE": caFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{"TARGET_URL": "https://10.0.0.5/"}
+			for k, v := range tt.vars {
+				vars[k] = v
+			}
+			u, err := loadUpstream(env(vars))
+			if err != nil {
+				t.Fatalf("loadUpstream() error = %v", err)
+			}
+			got, err := loadTLS(env(vars), u)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTLS() error = %v, want error %t", err, tt.wantErr)
+			}
+			if (got != nil) != tt.wantConfig {
+				t.Errorf("loadTLS() = %v, want a configuration %t", got, tt.wantConfig)
+			}
+			if got != nil && got.ServerName != "10.0.0.5" {
+				t.Errorf("loadTLS() server name = %q, want %q", got.ServerName, "10.0.0.5")
+			}
+		})
+	}
+}
@@ -64,6 +64,14 @@ resource "google_compute_instance" "internal_server" {
   tags                    = ["https-server", "allow-google-apis"]
   metadata_startup_script = replace(file("${abspath(path.module)}/web_server/internal_server_setup.sh"), "!PROXY_IP!", local.proxy_ip)
 
+  # Read by the startup script to serve HTTPS and require the client certificate of the function.
+  # Only the certificates are in the metadata, the private key is read from its secret.
+  metadata = {
+    mtls-server-cert       = tls_locally_signed_cert.server.cert_pem
+    mtls-ca                = tls_self_signed_cert.ca.cert_pem
+    mtls-server-key-secret = google_secret_manager_secret.mtls_server_key.id
+  }
+
   network_interface {
     subnetwork         = module.secure_harness.service_subnet[0]
     network_ip         = local.network_ip
@@ -77,6 +85,8 @@ resource "google_compute_instance" "internal_server" {
 
   depends_on = [
     google_service_account_iam_member.service_account_user,
+    google_secret_manager_secret_iam_member.mtls_server_key,
+    google_secret_manager_secret_version.mtls_server_key,
     module.secure_harness,
     module.secure_web_proxy
   ]
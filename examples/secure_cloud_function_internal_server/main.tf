@@ -15,9 +15,9 @@
 #  */
 
 locals {
-  location           = "us-west1"
-  region             = "us-west1"
-  zone               = "us-west1-b"
+  location           = var.location
+  region             = var.location
+  zone               = coalesce(var.zone, data.google_compute_zones.available.names[0])
   repository_name    = "rep-secure-cloud-function"
   network_ip         = "10.0.0.3"
   webserver_instance = "webserver"
@@ -27,6 +27,13 @@ locals {
   private_service_connect_ip = "10.3.0.5"
   cloud_services_sa          = "${module.secure_harness.serverless_project_numbers[module.secure_harness.serverless_project_ids[0]]}@cloudservices.gserviceaccount.com"
 }
+# Zones aren't named alike in every region: europe-west1 has no zone a.
+data "google_compute_zones" "available" {
+  project = module.secure_harness.serverless_project_ids[0]
+  region  = local.region
+  status  = "UP"
+}
+
 resource "random_id" "random_folder_suffix" {
   byte_length = 2
 }
@@ -72,6 +79,8 @@ module "secure_harness" {
     ]
   }
 
+  security_project_extra_apis = ["secretmanager.googleapis.com"]
+
   network_project_extra_apis = [
     "compute.googleapis.com",
     "networksecurity.googleapis.com",
@@ -259,8 +268,28 @@ module "secure_cloud_function" {
     PROJECT_ID = module.secure_harness.serverless_project_ids[0]
     NAME       = "cloud function v2"
     TARGET_IP  = local.network_ip
+
+    # Mirrors the fw-e-shared-restricted-internal-server egress firewall rule
+    ALLOWED_UPSTREAM_CIDRS = local.subnet_ip
+    ALLOWED_UPSTREAM_PORTS = "8000"
+
+    # Mutual TLS with the internal server, using the secret volumes below
+    TARGET_SCHEME        = "https"
+    TLS_CLIENT_CERT_FILE = "${local.mtls_mount_path}/client-cert/tls.crt"
+    TLS_CLIENT_KEY_FILE  = "${local.mtls_mount_path}/client-key/tls.key"
+    TLS_CA_FILE          = "${local.mtls_mount_path}/ca/ca.crt"
   }
 
+  secret_volumes = [for name, s in local.mtls_secrets : {
+    mount_path = "${local.mtls_mount_path}/${name}"
+    project_id = module.secure_harness.security_project_id
+    secret     = google_secret_manager_secret.mtls[name].secret_id
+    versions = [{
+      version = "latest"
+      path    = s.path
+    }]
+  }]
+
   event_trigger = {
     event_type            = "google.cloud.storage.object.v1.finalized"
     service_account_email = module.secure_harness.service_account_email[module.secure_harness.serverless_project_ids[0]]
@@ -278,6 +307,8 @@ module "secure_cloud_function" {
     google_storage_bucket_object.function-source,
     module.internal_server_firewall_rule,
     module.secure_web_proxy,
-    google_project_iam_member.network_service_agent_editor
+    google_project_iam_member.network_service_agent_editor,
+    google_secret_manager_secret_version.mtls,
+    google_secret_manager_secret_iam_member.mtls
   ]
 }
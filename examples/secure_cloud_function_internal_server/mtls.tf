@@ -0,0 +1,190 @@
+/**
+ * Copyright 2026 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+locals {
+  mtls_mount_path = "/etc/mtls"
+
+  # Files the function reads its client certificate, key and CA bundle from.
+  # Each secret is mounted on its own path with the latest version, so that
+  # rotating a secret version is picked up by the running function.
+  mtls_secrets = {
+    client-cert = { secret_id = "sct-mtls-client-cert", path = "tls.crt", data = tls_locally_signed_cert.client.cert_pem }
+    client-key  = { secret_id = "sct-mtls-client-key", path = "tls.key", data = tls_private_key.client.private_key_pem }
+    ca          = { secret_id = "sct-mtls-ca", path = "ca.crt", data = tls_self_signed_cert.ca.cert_pem }
+  }
+}
+
+# Example certificate authority for the mutual TLS connection between the
+# function and the internal server. The private keys are stored in the
+# Terraform state: use your own PKI, such as Certificate Authority Service,
+# for production workloads.
+resource "tls_private_key" "ca" {
+  algorithm   = "ECDSA"
+  ecdsa_curve = "P256"
+}
+
+resource "tls_self_signed_cert" "ca" {
+  private_key_pem       = tls_private_key.ca.private_key_pem
+  is_ca_certificate     = true
+  validity_period_hours = 8760
+  allowed_uses          = ["cert_signing", "crl_signing"]
+
+  subject {
+    common_name  = "Internal Server CA"
+    organization = "Secure Cloud Function Example"
+  }
+}
+
+resource "tls_private_key" "server" {
+  algorithm   = "ECDSA"
+  ecdsa_curve = "P256"
+}
+
+resource "tls_cert_request" "server" {
+  private_key_pem = tls_private_key.server.private_key_pem
+  ip_addresses    = [local.network_ip]
+
+  subject {
+    common_name = local.webserver_instance
+  }
+}
+
+resource "tls_locally_signed_cert" "server" {
+  cert_request_pem      = tls_cert_request.server.cert_request_pem
+  ca_private_key_pem    = tls_private_key.ca.private_key_pem
+  ca_cert_pem           = tls_self_signed_cert.ca.cert_pem
+  validity_period_hours = 2160
+  allowed_uses          = ["digital_signature", "key_encipherment", "server_auth"]
+}
+
+resource "tls_private_key" "client" {
+  algorithm   = "ECDSA"
+  ecdsa_curve = "P256"
+}
+
+resource "tls_cert_request" "client" {
+  private_key_pem = tls_private_key.client.private_key_pem
+
+  subject {
+    common_name = "secure-function2-internal-server"
+  }
+}
+
+resource "tls_locally_signed_cert" "client" {
+  cert_request_pem      = tls_cert_request.client.cert_request_pem
+  ca_private_key_pem    = tls_private_key.ca.private_key_pem
+  ca_cert_pem           = tls_self_signed_cert.ca.cert_pem
+  validity_period_hours = 2160
+  allowed_uses          = ["digital_signature", "key_encipherment", "client_auth"]
+}
+
+resource "google_project_service_identity" "secrets_sa" {
+  provider = google-beta
+
+  project    = module.secure_harness.security_project_id
+  service    = "secretmanager.googleapis.com"
+  depends_on = [module.secure_harness]
+}
+
+resource "time_sleep" "wait_secrets_identity_propagation" {
+  create_duration = "60s"
+
+  depends_on = [google_project_service_identity.secrets_sa]
+}
+
+module "mtls_kms" {
+  source  = "terraform-google-modules/kms/google"
+  version = "~> 4.0"
+
+  project_id           = module.secure_harness.security_project_id
+  location             = local.location
+  keyring              = "krg-secure-mtls"
+  keys                 = ["key-secure-mtls"]
+  set_decrypters_for   = ["key-secure-mtls"]
+  set_encrypters_for   = ["key-secure-mtls"]
+  decrypters           = ["serviceAccount:${google_project_service_identity.secrets_sa.email}"]
+  encrypters           = ["serviceAccount:${google_project_service_identity.secrets_sa.email}"]
+  prevent_destroy      = false
+  key_rotation_period  = "2592000s"
+  key_protection_level = "HSM"
+
+  depends_on = [time_sleep.wait_secrets_identity_propagation]
+}
+
+resource "google_secret_manager_secret" "mtls" {
+  for_each = local.mtls_secrets
+
+  secret_id = each.value.secret_id
+  project   = module.secure_harness.security_project_id
+
+  replication {
+    user_managed {
+      replicas {
+        location = local.location
+        customer_managed_encryption {
+          kms_key_name = module.mtls_kms.keys["key-secure-mtls"]
+        }
+      }
+    }
+  }
+}
+
+resource "google_secret_manager_secret_version" "mtls" {
+  for_each = local.mtls_secrets
+
+  secret      = google_secret_manager_secret.mtls[each.key].id
+  secret_data = each.value.data
+}
+
+resource "google_secret_manager_secret_iam_member" "mtls" {
+  for_each = local.mtls_secrets
+
+  project   = google_secret_manager_secret.mtls[each.key].project
+  secret_id = google_secret_manager_secret.mtls[each.key].secret_id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${module.secure_harness.service_account_email[module.secure_harness.serverless_project_ids[0]]}"
+}
+
+# The private key of the internal server is only readable by the service
+# account of the instance: the startup script reads it from Secret Manager
+# instead of the instance metadata, which any process on the instance can read.
+resource "google_secret_manager_secret" "mtls_server_key" {
+  secret_id = "sct-mtls-server-key"
+  project   = module.secure_harness.security_project_id
+
+  replication {
+    user_managed {
+      replicas {
+        location = local.location
+        customer_managed_encryption {
+          kms_key_name = module.mtls_kms.keys["key-secure-mtls"]
+        }
+      }
+    }
+  }
+}
+
+resource "google_secret_manager_secret_version" "mtls_server_key" {
+  secret      = google_secret_manager_secret.mtls_server_key.id
+  secret_data = tls_private_key.server.private_key_pem
+}
+
+resource "google_secret_manager_secret_iam_member" "mtls_server_key" {
+  project   = google_secret_manager_secret.mtls_server_key.project
+  secret_id = google_secret_manager_secret.mtls_server_key.secret_id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${module.compute_service_account.email}"
+}
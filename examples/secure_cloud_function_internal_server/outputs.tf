@@ -92,3 +92,13 @@ output "cloudfunction_url" {
   value       = module.secure_cloud_function.cloudfunction_url
   description = "The URL on which the deployed service is available."
 }
+
+output "location" {
+  value       = local.location
+  description = "The location where the resources were deployed."
+}
+
+output "zone" {
+  value       = local.zone
+  description = "The zone of the internal server instance."
+}
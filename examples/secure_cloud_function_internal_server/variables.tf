@@ -68,3 +68,15 @@ variable "ingress_policies" {
   }))
   default = []
 }
+
+variable "location" {
+  description = "The location where the resources are going to be deployed."
+  type        = string
+  default     = "us-west1"
+}
+
+variable "zone" {
+  description = "The zone of the internal server instance, in location. Defaults to the first zone of location that is up."
+  type        = string
+  default     = null
+}
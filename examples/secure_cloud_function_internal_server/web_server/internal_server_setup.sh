@@ -42,6 +42,31 @@ curl -sSO https://dl.google.com/cloudagents/add-google-cloud-ops-agent-repo.sh -
 sudo bash add-google-cloud-ops-agent-repo.sh --also-install
 sleep 60
 
+# Server certificate and the CA of the function client certificate
+mkdir -p /etc/mtls
+metadata() {
+  curl -sS --noproxy '*' -H "Metadata-Flavor: Google" "http://metadata.google.internal/computeMetadata/v1/instance/$1"
+}
+for attribute in mtls-server-cert mtls-ca; do
+  metadata "attributes/${attribute}" > "/etc/mtls/${attribute}.pem"
+done
+
+# Server key, read from Secret Manager with the service account of the instance.
+# Retried while the secret accessor grant propagates.
+secret=$(metadata attributes/mtls-server-key-secret)
+umask 077
+for attempt in $(seq 1 10); do
+  token=$(metadata service-accounts/default/token | python3 -c 'import json, sys; print(json.load(sys.stdin)["access_token"])')
+  if curl -sSf --noproxy '*' -H "Authorization: Bearer ${token}" \
+    "https://secretmanager.googleapis.com/v1/${secret}/versions/latest:access" |
+    python3 -c 'import base64, json, sys; sys.stdout.buffer.write(base64.b64decode(json.load(sys.stdin)["payload"]["data"]))' \
+      > /etc/mtls/mtls-server-key.pem; then
+    break
+  fi
+  sleep 30
+done
+umask 022
+
 tee -a /tmp/index.html <<'EOF'
 ----------- hello world --------------
 EOF
@@ -51,6 +76,7 @@ import http.server
 import socketserver
 import datetime
 import os
+import ssl
 
 PORT = 8000
 LOG_FILE = "/tmp/request_logs.log"
@@ -69,9 +95,17 @@ class RequestHandler(http.server.SimpleHTTPRequestHandler):
         # Call the parent class's do_GET method to handle the request
         super().do_GET()
 
+# Serve HTTPS and only accept clients with a certificate signed by the example CA
+context = ssl.SSLContext(ssl.PROTOCOL_TLS_SERVER)
+context.minimum_version = ssl.TLSVersion.TLSv1_2
+context.load_cert_chain("/etc/mtls/mtls-server-cert.pem", "/etc/mtls/mtls-server-key.pem")
+context.load_verify_locations("/etc/mtls/mtls-ca.pem")
+context.verify_mode = ssl.CERT_REQUIRED
+
 # Create the server with the custom request handler
 with socketserver.TCPServer(("", PORT), RequestHandler) as httpd:
-    print(f"Serving at port {PORT} from directory {DIRECTORY}")
+    httpd.socket = context.wrap_socket(httpd.socket, server_side=True)
+    print(f"Serving HTTPS with mutual TLS at port {PORT} from directory {DIRECTORY}")
     httpd.serve_forever()
 EOF
 
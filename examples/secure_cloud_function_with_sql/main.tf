@@ -16,9 +16,9 @@
 
 
 locals {
-  location        = "us-central1"
-  region          = "us-central1"
-  zone_sql        = "us-central1-a"
+  location        = var.location
+  region          = var.location
+  zone_sql        = coalesce(var.zone, data.google_compute_zones.available.names[0])
   repository_name = "rep-secure-cloud-function"
   db_name         = "db-application"
   db_user         = "app"
@@ -30,6 +30,13 @@ locals {
   cloud_services_sa = "${module.secure_harness.serverless_project_numbers[module.secure_harness.serverless_project_ids[0]]}@cloudservices.gserviceaccount.com"
 }
 
+# Zones aren't named alike in every region: europe-west1 has no zone a.
+data "google_compute_zones" "available" {
+  project = module.secure_harness.serverless_project_ids[1]
+  region  = local.region
+  status  = "UP"
+}
+
 resource "random_id" "random_folder_suffix" {
   byte_length = 2
 }
@@ -255,6 +262,7 @@ module "secure_web_proxy" {
     "*github.com/golang/*",
     "*github.com/google/*",
     "*github.com/googleapis/*",
+    "*github.com/go-sql-driver/*",
     "*github.com/json-iterator/go",
     "*github.com/modern-go/concurrent",
     "*github.com/modern-go/reflect2",
@@ -163,3 +163,8 @@ output "topic_id" {
   value       = module.pubsub.id
   description = "The Pub/Sub topic which will trigger Cloud Function."
 }
+
+output "location" {
+  value       = local.location
+  description = "The location where the resources were deployed."
+}
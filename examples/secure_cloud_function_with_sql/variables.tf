@@ -74,3 +74,15 @@ variable "time_to_wait_service_identity_propagation" {
   description = "The time to wait for service identity propagation."
   default     = "180s"
 }
+
+variable "location" {
+  description = "The location where the resources are going to be deployed."
+  type        = string
+  default     = "us-central1"
+}
+
+variable "zone" {
+  description = "The zone of the Cloud SQL instance, in location. Defaults to the first zone of location that is up."
+  type        = string
+  default     = null
+}
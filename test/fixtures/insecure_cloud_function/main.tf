@@ -0,0 +1,149 @@
+/**
+ * Copyright 2026 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+locals {
+  connector_id = "projects/${module.project.project_id}/locations/${var.location}/connectors/${google_vpc_access_connector.connector.name}"
+}
+
+# A dedicated project, so that the organization policies applied below don't
+# affect the other examples deployed in the CI project.
+module "project" {
+  source  = "terraform-google-modules/project-factory/google"
+  version = "~> 18.0"
+
+  name              = "ci-insecure-cf"
+  random_project_id = "true"
+  org_id            = var.org_id
+  folder_id         = var.folder_id
+  billing_account   = var.billing_account
+  deletion_policy   = "DELETE"
+
+  activate_apis = [
+    "artifactregistry.googleapis.com",
+    "cloudbuild.googleapis.com",
+    "cloudfunctions.googleapis.com",
+    "cloudkms.googleapis.com",
+    "compute.googleapis.com",
+    "orgpolicy.googleapis.com",
+    "run.googleapis.com",
+    "storage.googleapis.com",
+    "vpcaccess.googleapis.com",
+  ]
+}
+
+# The organization policies and key of the secure-cloud-function module, enforced
+# on the project before any function is created in it.
+module "cloud_function_security" {
+  source = "../../../modules/secure-cloud-function-security"
+
+  kms_project_id        = module.project.project_id
+  serverless_project_id = module.project.project_id
+  location              = var.location
+  keyring_name          = "krg-insecure-cloud-function"
+  key_name              = "key-insecure-cloud-function"
+  prevent_destroy       = false
+}
+
+resource "time_sleep" "wait_org_policies_propagation" {
+  create_duration = "180s"
+
+  depends_on = [module.cloud_function_security]
+}
+
+resource "google_compute_network" "vpc" {
+  name                    = "vpc-insecure-cloud-function"
+  project                 = module.project.project_id
+  auto_create_subnetworks = false
+}
+
+resource "google_compute_subnetwork" "connector" {
+  name          = "sb-insecure-cloud-function"
+  project       = module.project.project_id
+  region        = var.location
+  network       = google_compute_network.vpc.id
+  ip_cidr_range = "10.0.0.0/28"
+}
+
+resource "google_vpc_access_connector" "connector" {
+  name          = "con-insecure-cf"
+  project       = module.project.project_id
+  region        = var.location
+  machine_type  = "e2-micro"
+  min_instances = 2
+  max_instances = 3
+
+  subnet {
+    name = google_compute_subnetwork.connector.name
+  }
+}
+
+resource "google_service_account" "function" {
+  account_id   = "sa-insecure-cloud-function"
+  display_name = "Insecure Cloud Function"
+  project      = module.project.project_id
+}
+
+resource "google_storage_bucket" "source" {
+  name                        = "bkt-${module.project.project_id}-source"
+  project                     = module.project.project_id
+  location                    = var.location
+  force_destroy               = true
+  uniform_bucket_level_access = true
+}
+
+data "archive_file" "source" {
+  type        = "zip"
+  source_dir  = "${path.module}/../../../examples/secure_cloud_function_bigquery_trigger/functions/bq-to-cf"
+  output_path = "${path.module}/function-source.zip"
+}
+
+resource "google_storage_bucket_object" "source" {
+  name   = "src-${data.archive_file.source.output_md5}.zip"
+  bucket = google_storage_bucket.source.name
+  source = data.archive_file.source.output_path
+}
+
+# A deliberately insecure variant of the function, only created when
+# create_function is set. Every variant must be rejected by the organization
+# policies enforced above.
+resource "google_cloudfunctions2_function" "insecure" {
+  count = var.create_function ? 1 : 0
+
+  name     = "insecure-cloud-function"
+  project  = module.project.project_id
+  location = var.location
+
+  build_config {
+    runtime     = "go124"
+    entry_point = "HelloCloudFunction"
+
+    source {
+      storage_source {
+        bucket = google_storage_bucket.source.name
+        object = google_storage_bucket_object.source.name
+      }
+    }
+  }
+
+  service_config {
+    ingress_settings              = var.ingress_settings
+    vpc_connector                 = var.use_vpc_connector ? local.connector_id : null
+    vpc_connector_egress_settings = var.use_vpc_connector ? var.vpc_egress_value : null
+    service_account_email         = google_service_account.function.email
+  }
+
+  depends_on = [time_sleep.wait_org_policies_propagation]
+}
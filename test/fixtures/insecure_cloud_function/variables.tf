@@ -0,0 +1,60 @@
+/**
+ * Copyright 2026 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+variable "org_id" {
+  description = "The organization ID."
+  type        = string
+}
+
+variable "folder_id" {
+  description = "The folder to create the project of the insecure variants in."
+  type        = string
+}
+
+variable "billing_account" {
+  description = "The ID of the billing account to associate the project with."
+  type        = string
+}
+
+variable "location" {
+  description = "The location of the Cloud Function."
+  type        = string
+  default     = "us-west1"
+}
+
+variable "create_function" {
+  description = "Create the Cloud Function. The project, organization policies and VPC connector are created either way."
+  type        = bool
+  default     = false
+}
+
+variable "ingress_settings" {
+  description = "The ingress settings of the Cloud Function."
+  type        = string
+  default     = "ALLOW_INTERNAL_ONLY"
+}
+
+variable "vpc_egress_value" {
+  description = "The VPC connector egress settings of the Cloud Function."
+  type        = string
+  default     = "ALL_TRAFFIC"
+}
+
+variable "use_vpc_connector" {
+  description = "Send the egress of the Cloud Function through the VPC connector."
+  type        = bool
+  default     = true
+}
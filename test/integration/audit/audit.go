@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit checks a deployed Cloud Function against the secure baseline
+// enforced by the secure-cloud-function modules.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+const (
+	RuleVPCConnector       = "vpc-connector"
+	RuleEgressAllTraffic   = "egress-all-traffic"
+	RuleInternalIngress    = "internal-ingress"
+	RuleDedicatedSA        = "dedicated-service-account"
+	RuleCMEKRepository     = "cmek-artifact-repository"
+	RulePrivateWorkerPool  = "private-worker-pool"
+	defaultGCFRepositoryID = "gcf-artifacts"
+)
+
+var (
+	// allowedIngressSettings are the ingress settings that keep the function off the public internet.
+	allowedIngressSettings = []string{"ALLOW_INTERNAL_AND_GCLB", "ALLOW_INTERNAL_ONLY"}
+
+	// defaultServiceAccounts match the Google managed default identities a function falls back to.
+	defaultServiceAccounts = []*regexp.Regexp{
+		regexp.MustCompile(`^[0-9]+-compute@developer\.gserviceaccount\.com$`),
+		regexp.MustCompile(`@appspot\.gserviceaccount\.com$`),
+	}
+)
+
+// Violation is a single deviation from the secure baseline.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// ArtifactRepository is the output of `gcloud artifacts repositories describe`.
+type ArtifactRepository struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	KMSKeyName string `json:"kmsKeyName"`
+}
+
+// ParseArtifactRepository decodes the JSON document returned by `gcloud artifacts repositories describe`.
+func ParseArtifactRepository(data []byte) (ArtifactRepository, error) {
+	var repo ArtifactRepository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return ArtifactRepository{}, fmt.Errorf("error parsing artifact repository: %w", err)
+	}
+	return repo, nil
+}
+
+// Baseline holds the optional deployment specific values the function is checked against.
+// Empty values only check the generic rule, for example that any connector is set.
+type Baseline struct {
+	// VPCConnector is the expected connector id.
+	VPCConnector string
+	// ServiceAccountEmail is the expected runtime service account.
+	ServiceAccountEmail string
+	// Repository is the Artifact Registry repository used by the build.
+	// When nil the CMEK rule only checks that a dedicated repository is configured.
+	Repository *ArtifactRepository
+	// KMSKey is the expected key of Repository. When empty any customer managed key is accepted.
+	KMSKey string
+}
+
+// Audit returns every violation of the secure baseline found in cf.
+func Audit(cf testutils.CloudFunction, b Baseline) []Violation {
+	var violations []Violation
+	report := func(rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	sc := cf.ServiceConfig
+
+	// VPC connector
+	switch {
+	case sc.VPCConnector == "":
+		report(RuleVPCConnector, "function has no VPC connector")
+	case b.VPCConnector != "" && sc.VPCConnector != b.VPCConnector:
+		report(RuleVPCConnector, "VPC connector is %s, expected %s", sc.VPCConnector, b.VPCConnector)
+	}
+
+	// Egress
+	if sc.VPCConnectorEgressSettings != "ALL_TRAFFIC" {
+		report(RuleEgressAllTraffic, "VPC connector egress setting is %q, expected ALL_TRAFFIC", sc.VPCConnectorEgressSettings)
+	}
+
+	// Ingress
+	if !contains(allowedIngressSettings, sc.IngressSettings) {
+		report(RuleInternalIngress, "ingress setting is %q, expected one of %s", sc.IngressSettings, strings.Join(allowedIngressSettings, ", "))
+	}
+
+	// Service account
+	switch {
+	case sc.ServiceAccountEmail == "":
+		report(RuleDedicatedSA, "function has no runtime service account")
+	case isDefaultServiceAccount(sc.ServiceAccountEmail):
+		report(RuleDedicatedSA, "function runs as the default service account %s", sc.ServiceAccountEmail)
+	case b.ServiceAccountEmail != "" && sc.ServiceAccountEmail != b.ServiceAccountEmail:
+		report(RuleDedicatedSA, "function runs as %s, expected %s", sc.ServiceAccountEmail, b.ServiceAccountEmail)
+	}
+
+	// Artifact Registry
+	repo := cf.BuildConfig.DockerRepository
+	switch {
+	case repo == "":
+		report(RuleCMEKRepository, "build uses the default Artifact Registry repository instead of a CMEK protected one")
+	case testutils.GetLastSplitElement(repo, "/") == defaultGCFRepositoryID:
+		report(RuleCMEKRepository, "build uses the default repository %s instead of a CMEK protected one", repo)
+	case b.Repository != nil && b.Repository.Name != "" && b.Repository.Name != repo:
+		report(RuleCMEKRepository, "repository details are for %s, but the build uses %s", b.Repository.Name, repo)
+	case b.Repository != nil && b.Repository.KMSKeyName == "":
+		report(RuleCMEKRepository, "repository %s is not encrypted with a customer managed key", repo)
+	case b.Repository != nil && b.KMSKey != "" && b.Repository.KMSKeyName != b.KMSKey:
+		report(RuleCMEKRepository, "repository %s is encrypted with %s, expected %s", repo, b.Repository.KMSKeyName, b.KMSKey)
+	}
+
+	// Worker pool
+	if cf.BuildConfig.WorkerPool == "" {
+		report(RulePrivateWorkerPool, "build does not run in a private worker pool")
+	}
+
+	return violations
+}
+
+func isDefaultServiceAccount(email string) bool {
+	for _, re := range defaultServiceAccounts {
+		if re.MatchString(email) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
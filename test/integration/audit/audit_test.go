@@ -0,0 +1,159 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+const (
+	connectorID = "projects/prj-secure-cloud-function-25de/locations/us-west1/connectors/con-secure-cloud-function"
+	saEmail     = "sa-cloud-function@prj-secure-cloud-function-25de.iam.gserviceaccount.com"
+	repoID      = "projects/prj-secure-cloud-function-25de/locations/us-west1/repositories/rep-cloud-function-secure-cloud-function-bigquery"
+)
+
+func compliantFunction() testutils.CloudFunction {
+	return testutils.CloudFunction{
+		Name: "projects/prj-secure-cloud-function-25de/locations/us-west1/functions/secure-cloud-function-bigquery",
+		BuildConfig: testutils.BuildConfig{
+			DockerRepository: repoID,
+			WorkerPool:       "projects/prj-secure-cloud-function-25de/locations/us-west1/workerPools/workerpool",
+		},
+		ServiceConfig: testutils.ServiceConfig{
+			VPCConnector:               connectorID,
+			VPCConnectorEgressSettings: "ALL_TRAFFIC",
+			IngressSettings:            "ALLOW_INTERNAL_AND_GCLB",
+			ServiceAccountEmail:        saEmail,
+		},
+	}
+}
+
+func rules(violations []Violation) []string {
+	r := make([]string, 0, len(violations))
+	for _, v := range violations {
+		r = append(r, v.Rule)
+	}
+	return r
+}
+
+func TestAuditFunctionDescribe(t *testing.T) {
+	data, err := os.ReadFile("../../../function_describe.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := testutils.ParseCloudFunction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := Audit(cf, Baseline{VPCConnector: connectorID, ServiceAccountEmail: saEmail})
+	assert.ElementsMatch(t, []string{RuleEgressAllTraffic, RulePrivateWorkerPool}, rules(violations))
+}
+
+func TestAuditCompliant(t *testing.T) {
+	repo := &ArtifactRepository{Name: repoID, Format: "DOCKER", KMSKeyName: "projects/p/locations/us-west1/keyRings/k/cryptoKeys/c"}
+	assert.Empty(t, Audit(compliantFunction(), Baseline{VPCConnector: connectorID, ServiceAccountEmail: saEmail, Repository: repo, KMSKey: repo.KMSKeyName}))
+
+	internalOnly := compliantFunction()
+	internalOnly.ServiceConfig.IngressSettings = "ALLOW_INTERNAL_ONLY"
+	assert.Empty(t, Audit(internalOnly, Baseline{}))
+}
+
+func TestAuditViolations(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(cf *testutils.CloudFunction)
+		baseline Baseline
+		want     []string
+	}{
+		{
+			name:   "no connector",
+			mutate: func(cf *testutils.CloudFunction) { cf.ServiceConfig.VPCConnector = "" },
+			want:   []string{RuleVPCConnector},
+		},
+		{
+			name:     "unexpected connector",
+			mutate:   func(cf *testutils.CloudFunction) {},
+			baseline: Baseline{VPCConnector: "projects/p/locations/us-west1/connectors/other"},
+			want:     []string{RuleVPCConnector},
+		},
+		{
+			name:   "private ranges egress",
+			mutate: func(cf *testutils.CloudFunction) { cf.ServiceConfig.VPCConnectorEgressSettings = "PRIVATE_RANGES_ONLY" },
+			want:   []string{RuleEgressAllTraffic},
+		},
+		{
+			name:   "public ingress",
+			mutate: func(cf *testutils.CloudFunction) { cf.ServiceConfig.IngressSettings = "ALLOW_ALL" },
+			want:   []string{RuleInternalIngress},
+		},
+		{
+			name: "default compute service account",
+			mutate: func(cf *testutils.CloudFunction) {
+				cf.ServiceConfig.ServiceAccountEmail = "97410184241-compute@developer.gserviceaccount.com"
+			},
+			want: []string{RuleDedicatedSA},
+		},
+		{
+			name: "app engine service account",
+			mutate: func(cf *testutils.CloudFunction) {
+				cf.ServiceConfig.ServiceAccountEmail = "prj@appspot.gserviceaccount.com"
+			},
+			want: []string{RuleDedicatedSA},
+		},
+		{
+			name: "default repository",
+			mutate: func(cf *testutils.CloudFunction) {
+				cf.BuildConfig.DockerRepository = "projects/p/locations/us-west1/repositories/gcf-artifacts"
+			},
+			want: []string{RuleCMEKRepository},
+		},
+		{
+			name:     "repository without cmek",
+			mutate:   func(cf *testutils.CloudFunction) {},
+			baseline: Baseline{Repository: &ArtifactRepository{Name: repoID, Format: "DOCKER"}},
+			want:     []string{RuleCMEKRepository},
+		},
+		{
+			name:   "repository with another key",
+			mutate: func(cf *testutils.CloudFunction) {},
+			baseline: Baseline{
+				Repository: &ArtifactRepository{Name: repoID, Format: "DOCKER", KMSKeyName: "projects/p/locations/us-west1/keyRings/k/cryptoKeys/other"},
+				KMSKey:     "projects/p/locations/us-west1/keyRings/k/cryptoKeys/c",
+			},
+			want: []string{RuleCMEKRepository},
+		},
+		{
+			name:   "default worker pool",
+			mutate: func(cf *testutils.CloudFunction) { cf.BuildConfig.WorkerPool = "" },
+			want:   []string{RulePrivateWorkerPool},
+		},
+		{
+			name:   "empty document",
+			mutate: func(cf *testutils.CloudFunction) { *cf = testutils.CloudFunction{} },
+			want:   []string{RuleVPCConnector, RuleEgressAllTraffic, RuleInternalIngress, RuleDedicatedSA, RuleCMEKRepository, RulePrivateWorkerPool},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf := compliantFunction()
+			tt.mutate(&cf)
+			assert.Equal(t, tt.want, rules(Audit(cf, tt.baseline)))
+		})
+	}
+}
@@ -18,29 +18,70 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
 	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/plan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/report"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+const (
+	fixture     = "testdata/verify.json"
+	planFixture = "testdata/plan.json"
 )
 
 func TestGCF2GCSSource(t *testing.T) {
+	gc := testutils.NewGCloud(t, fixture)
 	gcs_sourceT := tft.NewTFBlueprintTest(t)
 
 	gcs_sourceT.DefineVerify(func(assert *assert.Assertions) {
-		// Removing DefaultVerify because Cloud Function API is changing the build_config/source/storage_source/generation and this modification is breaking the build validation.
-		// gcs_sourceT.DefaultVerify(assert)
+		plan.DefaultVerify(assert, gcs_sourceT, plan.VolatileAttributes)
+
+		verify(t, gc, gc.Outputs(t, gcs_sourceT.GetStringOutput))
+	})
+	gcs_sourceT.Test()
+}
 
-		function_name := gcs_sourceT.GetStringOutput("function_name")
-		projectID := gcs_sourceT.GetStringOutput("project_id")
-		location := gcs_sourceT.GetStringOutput("location")
+// TestGCF2GCSSourceReplay runs the verify stage against the recorded gcloud fixture.
+func TestGCF2GCSSourceReplay(t *testing.T) {
+	gc := testutils.NewReplayGCloud(t, fixture)
+	verify(t, gc, gc.Outputs(t, nil))
+}
+
+// TestGCF2GCSSourcePlan checks the planned resources of the example without deploying it.
+func TestGCF2GCSSourcePlan(t *testing.T) {
+	p, err := plan.Load(planFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	for _, f := range plan.Verify(p) {
+		assert.Fail("plan", f.String())
+	}
+	functions := p.Resources("google_cloudfunctions2_function")
+	if assert.Len(functions, 1) {
+		fn := functions[0].Values
+		assert.Equal("function2-gcs-source-py", fn.Get("name").String(), "function name")
+		assert.Len(fn.Get("build_config.0.source.0.storage_source").Array(), 1, "function should be built from the storage source")
+		assert.Empty(fn.Get("build_config.0.source.0.repo_source").Array(), "function should not have a repo source")
+		assert.Empty(fn.Get("event_trigger").Array(), "function should have no event trigger")
+	}
+}
 
-		function_cmd := gcloud.Run(t, "functions describe", gcloud.WithCommonArgs([]string{function_name, "--project", projectID, "--gen2", "--region", location, "--format", "json"}))
+func verify(t testing.TB, gc *testutils.GCloud, output func(string) string) {
+	function_name := output("function_name")
+	projectID := output("project_id")
+	location := output("location")
 
-		// T01: Verify if the Cloud Functions deployed is in ACTIVE state
-		assert.Equal("ACTIVE", function_cmd.Get("state").String(), fmt.Sprintf("Should be ACTIVE. Cloud Function is not successfully deployed."))
+	rep := report.New(t)
+	function := gc.DescribeCloudFunction(t, function_name, projectID, location)
 
-		// T02: Verify if the Cloud Functions is deployed from Storage Source by verifying a non-empty block
-		assert.NotEmpty(function_cmd.Get("buildConfig.source.storageSource").String(), fmt.Sprintf("Cloud Function is not deployed from Storage Source or maybe deployed from Repo Source"))
+	rep.Check(report.FunctionActive, function.Name, func(assert *report.Assertions) {
+		assert.Equal("ACTIVE", function.State, fmt.Sprintf("Should be ACTIVE. Cloud Function is not successfully deployed."))
+	})
+
+	// Verify if the Cloud Functions is deployed from Storage Source by verifying a non-empty block
+	rep.Check(report.FunctionSource, function.Name, func(assert *report.Assertions) {
+		assert.NotNil(function.BuildConfig.Source.StorageSource, fmt.Sprintf("Cloud Function is not deployed from Storage Source or maybe deployed from Repo Source"))
 	})
-	gcs_sourceT.Test()
 }
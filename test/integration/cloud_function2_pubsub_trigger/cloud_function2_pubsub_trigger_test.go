@@ -18,32 +18,80 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
 	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/plan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/report"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/resourcename"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+const (
+	fixture     = "testdata/verify.json"
+	planFixture = "testdata/plan.json"
 )
 
 func TestGCF2PubSubTrigger(t *testing.T) {
+	gc := testutils.NewGCloud(t, fixture)
 	pubsub_triggerT := tft.NewTFBlueprintTest(t)
 
 	pubsub_triggerT.DefineVerify(func(assert *assert.Assertions) {
-		// Removing DefaultVerify because Cloud Function API is changing the build_config/source/storage_source/generation and this modification is breaking the build validation.
-		// pubsub_triggerT.DefaultVerify(assert)
+		plan.DefaultVerify(assert, pubsub_triggerT, plan.VolatileAttributes)
+
+		verify(t, gc, gc.Outputs(t, pubsub_triggerT.GetStringOutput))
+	})
+	pubsub_triggerT.Test()
+}
 
-		function_name := pubsub_triggerT.GetStringOutput("function_name")
-		pubsubTopic := pubsub_triggerT.GetStringOutput("pubsub_topic")
-		projectID := pubsub_triggerT.GetStringOutput("project_id")
-		location := pubsub_triggerT.GetStringOutput("location")
+// TestGCF2PubSubTriggerReplay runs the verify stage against the recorded gcloud fixture.
+func TestGCF2PubSubTriggerReplay(t *testing.T) {
+	gc := testutils.NewReplayGCloud(t, fixture)
+	verify(t, gc, gc.Outputs(t, nil))
+}
+
+// TestGCF2PubSubTriggerPlan checks the planned resources of the example without deploying it.
+func TestGCF2PubSubTriggerPlan(t *testing.T) {
+	p, err := plan.Load(planFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	for _, f := range plan.Verify(p) {
+		assert.Fail("plan", f.String())
+	}
+	topic, ok := p.Resource("module.pubsub.google_pubsub_topic.topic[0]")
+	if assert.True(ok, "topic should be planned") {
+		assert.Equal("function2-topic", topic.Values.Get("name").String(), "topic name")
+	}
+	functions := p.Resources("google_cloudfunctions2_function")
+	if assert.Len(functions, 1) {
+		trigger := functions[0].Values.Get("event_trigger.0")
+		assert.Equal("google.cloud.pubsub.topic.v1.messagePublished", trigger.Get("event_type").String(), "event type")
+		assert.Equal("RETRY_POLICY_RETRY", trigger.Get("retry_policy").String(), "retry policy")
+		topicID := resourcename.Topic{Project: topic.Values.Get("project").String(), Name: topic.Values.Get("name").String()}.String()
+		assert.Equal(topicID, trigger.Get("pubsub_topic").String(), "function should be triggered by the example topic")
+	}
+}
 
-		function_cmd := gcloud.Run(t, "functions describe", gcloud.WithCommonArgs([]string{function_name, "--project", projectID, "--gen2", "--region", location, "--format", "json"}))
+func verify(t testing.TB, gc *testutils.GCloud, output func(string) string) {
+	function_name := output("function_name")
+	pubsubTopic := output("pubsub_topic")
+	projectID := output("project_id")
+	location := output("location")
 
-		// T01: Verify if the Cloud Functions deployed is in ACTIVE state
-		assert.Equal("ACTIVE", function_cmd.Get("state").String(), fmt.Sprintf("Should be ACTIVE. Cloud Function is not successfully deployed."))
+	rep := report.New(t)
+	function := gc.DescribeCloudFunction(t, function_name, projectID, location)
 
-		// T02: Verify if the Cloud Functions with PubSub Event Trigger is deployed matching the output
-		// Topic format: projects/<PROJECT_ID>/topic/<TOPICNAME>
-		// Output: <TOPICNAME>
-		assert.Contains(function_cmd.Get("eventTrigger.pubsubTopic").String(), pubsubTopic, fmt.Sprintf("Event Trigger is not based on PubSub Topic provided in variables. Check the EventType configuration."))
+	rep.Check(report.FunctionActive, function.Name, func(assert *report.Assertions) {
+		assert.Equal("ACTIVE", function.State, fmt.Sprintf("Should be ACTIVE. Cloud Function is not successfully deployed."))
+	})
+
+	// Verify if the Cloud Functions with PubSub Event Trigger is deployed matching the output
+	// Output: <TOPICNAME>
+	topic := resourcename.Topic{Project: projectID, Name: pubsubTopic}
+	rep.Check(report.FunctionTrigger, function.Name, func(assert *report.Assertions) {
+		if assert.NotNil(function.EventTrigger, "Event Trigger should exist.") {
+			assert.Equal(topic.String(), function.EventTrigger.PubsubTopic, fmt.Sprintf("Event Trigger is not based on PubSub Topic %s provided in variables. Check the EventType configuration.", topic))
+		}
 	})
-	pubsub_triggerT.Test()
 }
@@ -0,0 +1,116 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// cfaudit reports the secure baseline violations of a Cloud Function.
+//
+// Usage:
+//
+//	gcloud functions describe NAME --gen2 --region REGION --format json > function.json
+//	cfaudit [-connector ID] [-service-account EMAIL] [-repository repo.json] [-json] function.json
+//
+// Use "-" as the file name to read the function from stdin.
+// The exit code is 1 when violations are found and 2 on usage errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cfaudit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	connector := fs.String("connector", "", "expected VPC connector id")
+	serviceAccount := fs.String("service-account", "", "expected runtime service account email")
+	repository := fs.String("repository", "", "path to the `gcloud artifacts repositories describe --format json` output of the build repository")
+	asJSON := fs.Bool("json", false, "print violations as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: cfaudit [flags] function.json")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	data, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cf, err := testutils.ParseCloudFunction(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	b := audit.Baseline{
+		VPCConnector:        *connector,
+		ServiceAccountEmail: *serviceAccount,
+	}
+	if *repository != "" {
+		data, err := os.ReadFile(*repository)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		repo, err := audit.ParseArtifactRepository(data)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		b.Repository = &repo
+	}
+
+	violations := audit.Audit(cf, b)
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if violations == nil {
+			violations = []audit.Violation{}
+		}
+		if err := enc.Encode(violations); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Fprintln(stdout, v)
+		}
+		if len(violations) == 0 {
+			fmt.Fprintf(stdout, "%s complies with the secure baseline\n", cf.Name)
+		}
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
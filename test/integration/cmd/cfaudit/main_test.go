@@ -0,0 +1,53 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const functionDescribePath = "../../../../function_describe.json"
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{functionDescribePath}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "egress-all-traffic: ")
+	assert.Contains(t, stdout.String(), "private-worker-pool: ")
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunStdinJSON(t *testing.T) {
+	data, err := os.ReadFile(functionDescribePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-json", "-"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(stdout.String(), "["), "output should be a JSON list")
+	assert.Contains(t, stdout.String(), `"rule": "egress-all-traffic"`)
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"missing.json"}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// swpcheck reports the Go modules of a Cloud Function that the Secure Web Proxy
+// URL list of a blueprint does not allow the build to download.
+//
+// Usage:
+//
+//	swpcheck -tf examples/secure_cloud_function_with_sql/main.tf examples/secure_cloud_function_with_sql/functions/cf-to-sql
+//
+// The modules are checked against the hosts the build connects to with -goproxy,
+// the module proxy by default, or the hosts of their paths and repositories with direct.
+//
+// The exit code is 1 when modules are not covered and 2 on usage errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/swp"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("swpcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tf := fs.String("tf", "", "Terraform file calling the secure-web-proxy module")
+	goproxy := fs.String("goproxy", swp.DefaultGOPROXY, "GOPROXY of the build")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *tf == "" || fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: swpcheck -tf main.tf function-dir")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	l, err := swp.URLListFromTerraform(*tf)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	uncovered, err := swp.Uncovered(fs.Arg(0), l, *goproxy)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	for _, m := range uncovered {
+		fmt.Fprintf(stdout, "%s is not covered by url_lists\n", m)
+	}
+	if len(uncovered) > 0 {
+		return 1
+	}
+	fmt.Fprintf(stdout, "url_lists cover every module of %s\n", fs.Arg(0))
+	return 0
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sqlExample = "../../../../examples/secure_cloud_function_with_sql"
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-tf", sqlExample + "/main.tf", sqlExample + "/functions/cf-to-sql"}, &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "url_lists cover every module")
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunUncovered(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-tf", "../../../../examples/secure_cloud_function_internal_server/main.tf", "-goproxy", "direct", sqlExample + "/functions/cf-to-sql"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "golang.org/x/sync is not covered by url_lists")
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-tf", "missing.tf", "."}, &stdout, &stderr))
+}
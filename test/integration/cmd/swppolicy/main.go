@@ -0,0 +1,110 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// swppolicy evaluates sample requests against Secure Web Proxy gateway security policy rules.
+//
+// Usage:
+//
+//	gcloud network-security gateway-security-policies rules list --gateway-security-policy POLICY --location LOCATION --format json > rules.json
+//	swppolicy -rules rules.json -url-list projects/P/locations/L/urlLists/N -tf main.tf [-source-ip IP] [-service-account EMAIL] HOST...
+//
+// The URL list values are read from the url_lists argument of the secure-web-proxy module call in -tf.
+// The exit code is 1 when a request is denied and 2 on usage errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/swp"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("swppolicy", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesPath := fs.String("rules", "", "path to the `gcloud network-security gateway-security-policies rules list --format json` output")
+	urlList := fs.String("url-list", "", "resource name of the URL list referenced by the rules")
+	tf := fs.String("tf", "", "Terraform file calling the secure-web-proxy module")
+	sourceIP := fs.String("source-ip", "", "source IP of the requests")
+	serviceAccount := fs.String("service-account", "", "source service account of the requests")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rulesPath == "" || fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: swppolicy -rules rules.json [flags] host...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	data, err := os.ReadFile(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rules, err := swp.ParseRules(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	lists := map[string]swp.URLList{}
+	if *tf != "" {
+		l, err := swp.URLListFromTerraform(*tf)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		lists[*urlList] = l
+	}
+	policy, err := swp.NewPolicy(rules, lists)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	req := swp.Request{ServiceAccount: *serviceAccount}
+	if *sourceIP != "" {
+		if req.SourceIP, err = netip.ParseAddr(*sourceIP); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	code := 0
+	for _, host := range fs.Args() {
+		req.Host = host
+		v, err := policy.Evaluate(req)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		switch {
+		case v.Rule == "":
+			fmt.Fprintf(stdout, "%s: denied, no rule matches\n", host)
+		case v.Allowed:
+			fmt.Fprintf(stdout, "%s: allowed by %s\n", host, v.Rule)
+		default:
+			fmt.Fprintf(stdout, "%s: denied by %s\n", host, v.Rule)
+		}
+		if !v.Allowed {
+			code = 1
+		}
+	}
+	return code
+}
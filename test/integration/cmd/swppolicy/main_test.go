@@ -0,0 +1,56 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const urlList = "projects/prj-restricted-shared/locations/us-west1/urlLists/swp-url-lists"
+
+func writeRules(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	rules := `[{"name": "swp-security-policy-rule", "enabled": true, "priority": 1, "basicProfile": "ALLOW", "sessionMatcher": "inUrlList(host(), '` + urlList + `')"}]`
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-rules", writeRules(t),
+		"-url-list", urlList,
+		"-tf", "../../../../examples/secure_cloud_function_bigquery_trigger/main.tf",
+		"cloud.google.com", "example.com",
+	}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "cloud.google.com: allowed by swp-security-policy-rule\nexample.com: denied, no rule matches\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-rules", "missing.json", "github.com"}, &stdout, &stderr))
+	// The rules reference a URL list that was not loaded.
+	assert.Equal(t, 2, run([]string{"-rules", writeRules(t), "github.com"}, &stdout, &stderr))
+}
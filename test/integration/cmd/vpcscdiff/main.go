@@ -0,0 +1,139 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// vpcscdiff compares a VPC Service Controls perimeter, or an older catalog,
+// with the restricted services catalog.
+//
+// Usage:
+//
+//	gcloud access-context-manager perimeters describe PERIMETER --policy POLICY --format json > perimeter.json
+//	vpcscdiff [-catalog restricted_services.yaml] [-json] perimeter.json
+//	vpcscdiff [-catalog restricted_services.yaml] -old old_restricted_services.yaml
+//
+// Use "-" as the file name to read the perimeter from stdin. Without -catalog the
+// catalog shipped with the tests is used.
+// The exit code is 1 when the perimeter does not restrict a catalog service and 2 on usage errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/vpcsc"
+	"github.com/tidwall/gjson"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("vpcscdiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	catalogPath := fs.String("catalog", "", "restricted services catalog, defaults to the catalog shipped with the tests")
+	oldPath := fs.String("old", "", "older restricted services catalog to compare the catalog with")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*oldPath == "") == (fs.NArg() != 1) {
+		fmt.Fprintln(stderr, "usage: vpcscdiff [flags] perimeter.json | vpcscdiff [flags] -old catalog.yaml")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	catalog := vpcsc.Default()
+	if *catalogPath != "" {
+		c, err := vpcsc.LoadFile(*catalogPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		catalog = c
+	}
+
+	if *oldPath != "" {
+		old, err := vpcsc.LoadFile(*oldPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		d := catalog.Diff(old)
+		if *asJSON {
+			return encode(d, stdout, stderr)
+		}
+		fmt.Fprintf(stdout, "restricted services catalog version %d to %d\n", old.Version, catalog.Version)
+		for _, s := range d.Added {
+			fmt.Fprintf(stdout, "added: %s\n", s)
+		}
+		for _, s := range d.Removed {
+			fmt.Fprintf(stdout, "removed: %s\n", s)
+		}
+		for _, s := range d.Deprecated {
+			fmt.Fprintf(stdout, "deprecated: %s\n", s)
+		}
+		return 0
+	}
+
+	data, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if !gjson.ValidBytes(data) {
+		fmt.Fprintf(stderr, "%s is not valid JSON\n", fs.Arg(0))
+		return 2
+	}
+	var restricted []string
+	for _, s := range gjson.GetBytes(data, "status.restrictedServices").Array() {
+		restricted = append(restricted, s.String())
+	}
+	r := catalog.Compare(restricted)
+	if *asJSON {
+		if code := encode(r, stdout, stderr); code != 0 {
+			return code
+		}
+	} else {
+		for _, l := range r.Lines() {
+			fmt.Fprintln(stdout, l)
+		}
+		if len(r.Lines()) == 0 {
+			fmt.Fprintf(stdout, "perimeter matches restricted services catalog version %d\n", catalog.Version)
+		}
+	}
+	if len(r.Missing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func encode(v any, stdout, stderr io.Writer) int {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const perimeter = `{"name": "accessPolicies/1/servicePerimeters/p", "status": {"restrictedServices": ["bigquery.googleapis.com", "clouddebugger.googleapis.com", "example.googleapis.com"]}}`
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunPerimeter(t *testing.T) {
+	catalog := writeFile(t, "catalog.yaml", "version: 3\nservices:\n  - name: bigquery.googleapis.com\n  - name: clouddebugger.googleapis.com\n    deprecated: true\n")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-catalog", catalog, "-"}, strings.NewReader(perimeter), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "extra: example.googleapis.com is restricted by the perimeter but not in the catalog\n"+
+		"deprecated: clouddebugger.googleapis.com is deprecated but still restricted by the perimeter\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	code = run([]string{"-json", "-"}, strings.NewReader(perimeter), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), `"cloudfunctions.googleapis.com"`)
+}
+
+func TestRunOld(t *testing.T) {
+	old := writeFile(t, "old.yaml", "version: 1\nservices:\n  - name: bigquery.googleapis.com\n  - name: retired.googleapis.com\n")
+	catalog := writeFile(t, "catalog.yaml", "version: 2\nservices:\n  - name: bigquery.googleapis.com\n  - name: run.googleapis.com\n")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-catalog", catalog, "-old", old}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "restricted services catalog version 1 to 2\nadded: run.googleapis.com\nremoved: retired.googleapis.com\n", stdout.String())
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"missing.json"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-old", "old.yaml", "perimeter.json"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-"}, strings.NewReader("{"), &stdout, &stderr))
+}
@@ -0,0 +1,312 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmek checks that the customer managed encryption keys of a deployment
+// are consistent with the resources they encrypt: same location, the expected
+// rotation period and protection level, and grants for the service agents that use them.
+package cmek
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/resourcename"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	CheckLocation        = "key-location"
+	CheckRotation        = "key-rotation"
+	CheckProtectionLevel = "key-protection-level"
+	CheckGrants          = "key-grants"
+
+	roleEncrypterDecrypter = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
+	roleEncrypter          = "roles/cloudkms.cryptoKeyEncrypter"
+	roleDecrypter          = "roles/cloudkms.cryptoKeyDecrypter"
+)
+
+// keyFields are the fields of gcloud describe results that hold a key name.
+var keyFields = []string{"kmsKeyName", "defaultKmsKeyName", "cryptoKeyName", "kmsKeyVersionName"}
+
+// Service is a Google service that encrypts data with a customer managed key through its service agent.
+type Service string
+
+const (
+	ServiceArtifactRegistry Service = "artifactregistry"
+	ServiceBigQuery         Service = "bigquery"
+	ServiceCloudFunctions   Service = "cloudfunctions"
+	ServiceCloudSQL         Service = "cloudsql"
+	ServiceEventarc         Service = "eventarc"
+	ServicePubSub           Service = "pubsub"
+	ServiceSecretManager    Service = "secretmanager"
+	ServiceStorage          Service = "storage"
+)
+
+var serviceAgents = map[Service]string{
+	ServiceArtifactRegistry: "service-%s@gcp-sa-artifactregistry.iam.gserviceaccount.com",
+	ServiceBigQuery:         "bq-%s@bigquery-encryption.iam.gserviceaccount.com",
+	ServiceCloudFunctions:   "service-%s@gcf-admin-robot.iam.gserviceaccount.com",
+	ServiceCloudSQL:         "service-%s@gcp-sa-cloud-sql.iam.gserviceaccount.com",
+	ServiceEventarc:         "service-%s@gcp-sa-eventarc.iam.gserviceaccount.com",
+	ServicePubSub:           "service-%s@gcp-sa-pubsub.iam.gserviceaccount.com",
+	ServiceSecretManager:    "service-%s@gcp-sa-secretmanager.iam.gserviceaccount.com",
+	ServiceStorage:          "service-%s@gs-project-accounts.iam.gserviceaccount.com",
+}
+
+// ServiceAgent returns the IAM member of the service agent of s in the project.
+func ServiceAgent(s Service, projectNumber string) string {
+	format, ok := serviceAgents[s]
+	if !ok {
+		panic(fmt.Sprintf("unknown service %q", s))
+	}
+	return "serviceAccount:" + fmt.Sprintf(format, projectNumber)
+}
+
+// Usage is a resource encrypted with a key.
+type Usage struct {
+	Resource string
+	Kind     string
+	// Location is the location of the resource. Empty for global resources, such as Pub/Sub topics.
+	Location string
+	// Key is the crypto key name.
+	Key string
+	// ServiceAgents are the members that encrypt and decrypt with the key for the resource.
+	ServiceAgents []string
+}
+
+// Collect returns a usage for every key named in the kmsKeyName, defaultKmsKeyName,
+// cryptoKeyName and kmsKeyVersionName fields of doc, the gcloud describe result of resource.
+func Collect(resource, kind, location string, doc gjson.Result, agents ...string) []Usage {
+	var usages []Usage
+	var walk func(r gjson.Result)
+	walk = func(r gjson.Result) {
+		r.ForEach(func(k, v gjson.Result) bool {
+			if v.IsObject() || v.IsArray() {
+				walk(v)
+				return true
+			}
+			if k.Type != gjson.String || !slices.Contains(keyFields, k.String()) || v.String() == "" {
+				return true
+			}
+			key := v.String()
+			if version, err := resourcename.ParseCryptoKeyVersion(key); err == nil {
+				key = version.CryptoKey.String()
+			}
+			if !slices.ContainsFunc(usages, func(u Usage) bool { return u.Key == key }) {
+				usages = append(usages, Usage{Resource: resource, Kind: kind, Location: location, Key: key, ServiceAgents: agents})
+			}
+			return true
+		})
+	}
+	walk(doc)
+	return usages
+}
+
+// Key is a crypto key and the members allowed to use it.
+type Key struct {
+	Name            resourcename.CryptoKey
+	RotationPeriod  time.Duration
+	ProtectionLevel string
+	Encrypters      []string
+	Decrypters      []string
+}
+
+// ParseKey decodes the results of `gcloud kms keys describe` and `gcloud kms keys get-iam-policy`.
+func ParseKey(describe, policy []byte) (Key, error) {
+	var k struct {
+		Name           string `json:"name"`
+		RotationPeriod string `json:"rotationPeriod"`
+		Primary        struct {
+			ProtectionLevel string `json:"protectionLevel"`
+		} `json:"primary"`
+		VersionTemplate struct {
+			ProtectionLevel string `json:"protectionLevel"`
+		} `json:"versionTemplate"`
+	}
+	if err := json.Unmarshal(describe, &k); err != nil {
+		return Key{}, fmt.Errorf("error parsing crypto key: %w", err)
+	}
+	name, err := resourcename.ParseCryptoKey(k.Name)
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{Name: name, ProtectionLevel: k.VersionTemplate.ProtectionLevel}
+	if key.ProtectionLevel == "" {
+		key.ProtectionLevel = k.Primary.ProtectionLevel
+	}
+	if k.RotationPeriod != "" {
+		if key.RotationPeriod, err = time.ParseDuration(k.RotationPeriod); err != nil {
+			return Key{}, fmt.Errorf("crypto key %s has rotation period %q: %w", k.Name, k.RotationPeriod, err)
+		}
+	}
+
+	var p struct {
+		Bindings []struct {
+			Role    string   `json:"role"`
+			Members []string `json:"members"`
+		} `json:"bindings"`
+	}
+	if err := json.Unmarshal(policy, &p); err != nil {
+		return Key{}, fmt.Errorf("error parsing IAM policy of crypto key %s: %w", k.Name, err)
+	}
+	for _, b := range p.Bindings {
+		switch b.Role {
+		case roleEncrypterDecrypter:
+			key.Encrypters = append(key.Encrypters, b.Members...)
+			key.Decrypters = append(key.Decrypters, b.Members...)
+		case roleEncrypter:
+			key.Encrypters = append(key.Encrypters, b.Members...)
+		case roleDecrypter:
+			key.Decrypters = append(key.Decrypters, b.Members...)
+		}
+	}
+	return key, nil
+}
+
+// Finding is a usage that is not consistent with its key.
+type Finding struct {
+	Check    string
+	Resource string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Check, f.Resource, f.Message)
+}
+
+// Row is the coverage of a resource by a key.
+type Row struct {
+	Usage
+	Key      Key
+	Granted  int
+	Findings []Finding
+}
+
+// Table is the CMEK coverage of a deployment.
+type Table struct {
+	Rows []Row
+}
+
+// Verify checks every usage against its key, described by describe, and the
+// expectation of its key ring. Key rings without an expectation use DefaultExpectation.
+func Verify(usages []Usage, describe func(key string) (Key, error), exps map[string]Expectation) (Table, error) {
+	keys := map[string]Key{}
+	var t Table
+	for _, u := range usages {
+		key, ok := keys[u.Key]
+		if !ok {
+			if _, err := resourcename.ParseCryptoKey(u.Key); err != nil {
+				return Table{}, fmt.Errorf("%s: %w", u.Resource, err)
+			}
+			var err error
+			if key, err = describe(u.Key); err != nil {
+				return Table{}, fmt.Errorf("error describing crypto key %s: %w", u.Key, err)
+			}
+			keys[u.Key] = key
+		}
+		exp, ok := exps[key.Name.KeyRing]
+		if !ok {
+			exp = DefaultExpectation
+		}
+		t.Rows = append(t.Rows, check(u, key, exp))
+	}
+	return t, nil
+}
+
+func check(u Usage, key Key, exp Expectation) Row {
+	row := Row{Usage: u, Key: key}
+	report := func(check, format string, args ...interface{}) {
+		row.Findings = append(row.Findings, Finding{Check: check, Resource: u.Resource, Message: fmt.Sprintf(format, args...)})
+	}
+	if u.Location != "" && !strings.EqualFold(u.Location, key.Name.Location) {
+		report(CheckLocation, "%s is in %s but its key %s is in %s", u.Kind, strings.ToLower(u.Location), key.Name, key.Name.Location)
+	}
+	switch {
+	case key.RotationPeriod == 0:
+		report(CheckRotation, "key %s has no rotation period, expected %s", key.Name, formatPeriod(exp.RotationPeriod))
+	case key.RotationPeriod != exp.RotationPeriod:
+		report(CheckRotation, "key %s rotates every %s, expected %s", key.Name, formatPeriod(key.RotationPeriod), formatPeriod(exp.RotationPeriod))
+	}
+	if key.ProtectionLevel != exp.ProtectionLevel {
+		report(CheckProtectionLevel, "key %s has protection level %q, expected %s", key.Name, key.ProtectionLevel, exp.ProtectionLevel)
+	}
+	for _, agent := range u.ServiceAgents {
+		var missing []string
+		if !slices.Contains(key.Encrypters, agent) {
+			missing = append(missing, "encrypter")
+		}
+		if !slices.Contains(key.Decrypters, agent) {
+			missing = append(missing, "decrypter")
+		}
+		if len(missing) > 0 {
+			report(CheckGrants, "%s is not %s of key %s", agent, strings.Join(missing, " and "), key.Name)
+			continue
+		}
+		row.Granted++
+	}
+	return row
+}
+
+// Findings returns the findings of every row.
+func (t Table) Findings() []Finding {
+	var findings []Finding
+	for _, r := range t.Rows {
+		findings = append(findings, r.Findings...)
+	}
+	return findings
+}
+
+// String formats the table with one line per resource and key.
+func (t Table) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tKIND\tLOCATION\tKEY RING\tKEY\tKEY LOCATION\tROTATION\tPROTECTION\tGRANTS\tRESULT")
+	for _, r := range t.Rows {
+		location := strings.ToLower(r.Location)
+		if location == "" {
+			location = "-"
+		}
+		result := "OK"
+		if len(r.Findings) > 0 {
+			checks := make([]string, 0, len(r.Findings))
+			for _, f := range r.Findings {
+				if !slices.Contains(checks, f.Check) {
+					checks = append(checks, f.Check)
+				}
+			}
+			result = strings.Join(checks, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\n", r.Resource, r.Kind, location,
+			r.Key.Name.KeyRing, r.Key.Name.Name, r.Key.Name.Location, formatPeriod(r.Key.RotationPeriod),
+			r.Key.ProtectionLevel, r.Granted, len(r.ServiceAgents), result)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// formatPeriod formats a rotation period in whole days when possible, as in 30d.
+func formatPeriod(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	default:
+		return d.String()
+	}
+}
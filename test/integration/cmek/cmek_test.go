@@ -0,0 +1,167 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmek
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	functionKey = "projects/prj-security-cf-0f92/locations/us-west1/keyRings/krg-secure-cloud-function/cryptoKeys/key-secure-cloud-function"
+	bigqueryKey = "projects/prj-security-cf-0f92/locations/us-west1/keyRings/krg-secure-bigquery/cryptoKeys/key-secure-bigquery"
+	storageSA   = "serviceAccount:service-97410184241@gs-project-accounts.iam.gserviceaccount.com"
+)
+
+func keyDescribe(name, rotation, level string) []byte {
+	return []byte(fmt.Sprintf(`{"name": %q, "rotationPeriod": %q, "primary": {"protectionLevel": %q}, "versionTemplate": {"protectionLevel": %q}}`, name, rotation, level, level))
+}
+
+func TestServiceAgent(t *testing.T) {
+	assert.Equal(t, storageSA, ServiceAgent(ServiceStorage, "97410184241"))
+	assert.Equal(t, "serviceAccount:bq-97410184241@bigquery-encryption.iam.gserviceaccount.com", ServiceAgent(ServiceBigQuery, "97410184241"))
+}
+
+func TestCollect(t *testing.T) {
+	secret := gjson.Parse(`{
+		"name": "projects/p/secrets/s",
+		"replication": {"userManaged": {"replicas": [
+			{"customerManagedEncryption": {"kmsKeyName": "` + functionKey + `"}, "location": "us-west1"},
+			{"customerManagedEncryption": {"kmsKeyVersionName": "` + functionKey + `/cryptoKeyVersions/3"}, "location": "us-west1"},
+			{"customerManagedEncryption": {"kmsKeyName": "` + bigqueryKey + `"}, "location": "us-west1"}
+		]}}
+	}`)
+	usages := Collect("projects/p/secrets/s", "Secret Manager secret", "us-west1", secret, "serviceAccount:agent")
+	assert.Equal(t, []Usage{
+		{Resource: "projects/p/secrets/s", Kind: "Secret Manager secret", Location: "us-west1", Key: functionKey, ServiceAgents: []string{"serviceAccount:agent"}},
+		{Resource: "projects/p/secrets/s", Kind: "Secret Manager secret", Location: "us-west1", Key: bigqueryKey, ServiceAgents: []string{"serviceAccount:agent"}},
+	}, usages)
+	assert.Empty(t, Collect("projects/p/topics/t", "Pub/Sub topic", "", gjson.Parse(`{"name": "projects/p/topics/t"}`)))
+}
+
+func TestParseKey(t *testing.T) {
+	policy := []byte(`{"bindings": [
+		{"role": "roles/cloudkms.cryptoKeyEncrypterDecrypter", "members": ["serviceAccount:both"]},
+		{"role": "roles/cloudkms.cryptoKeyEncrypter", "members": ["serviceAccount:encrypter"]},
+		{"role": "roles/cloudkms.cryptoKeyDecrypter", "members": ["serviceAccount:decrypter"]},
+		{"role": "roles/cloudkms.admin", "members": ["group:admins"]}
+	]}`)
+	key, err := ParseKey(keyDescribe(functionKey, "2592000s", "HSM"), policy)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "krg-secure-cloud-function", key.Name.KeyRing)
+		assert.Equal(t, 30*24*time.Hour, key.RotationPeriod)
+		assert.Equal(t, "HSM", key.ProtectionLevel)
+		assert.Equal(t, []string{"serviceAccount:both", "serviceAccount:encrypter"}, key.Encrypters)
+		assert.Equal(t, []string{"serviceAccount:both", "serviceAccount:decrypter"}, key.Decrypters)
+	}
+
+	_, err = ParseKey(keyDescribe("key-secure-cloud-function", "2592000s", "HSM"), policy)
+	assert.Error(t, err)
+	_, err = ParseKey(keyDescribe(functionKey, "30 days", "HSM"), policy)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	policy := []byte(`{"bindings": [
+		{"role": "roles/cloudkms.cryptoKeyEncrypter", "members": ["` + storageSA + `", "serviceAccount:encrypter-only"]},
+		{"role": "roles/cloudkms.cryptoKeyDecrypter", "members": ["` + storageSA + `"]}
+	]}`)
+	describes := map[string][]byte{
+		functionKey: keyDescribe(functionKey, "2592000s", "HSM"),
+		bigqueryKey: keyDescribe(bigqueryKey, "7776000s", "SOFTWARE"),
+	}
+	calls := 0
+	describe := func(key string) (Key, error) {
+		calls++
+		return ParseKey(describes[key], policy)
+	}
+
+	tests := []struct {
+		name  string
+		usage Usage
+		want  []string
+	}{
+		{
+			name:  "consistent",
+			usage: Usage{Resource: "gcf-v2-sources", Kind: "Storage bucket", Location: "US-WEST1", Key: functionKey, ServiceAgents: []string{storageSA}},
+		},
+		{
+			name:  "global resource",
+			usage: Usage{Resource: "projects/p/topics/t", Kind: "Pub/Sub topic", Key: functionKey},
+		},
+		{
+			name:  "other location",
+			usage: Usage{Resource: "tbl_test", Kind: "BigQuery table", Location: "us-central1", Key: functionKey},
+			want:  []string{"key-location: tbl_test: BigQuery table is in us-central1 but its key " + functionKey + " is in us-west1"},
+		},
+		{
+			name:  "missing grants",
+			usage: Usage{Resource: "repo", Kind: "Artifact Registry repository", Location: "us-west1", Key: functionKey, ServiceAgents: []string{"serviceAccount:encrypter-only", "serviceAccount:none"}},
+			want: []string{
+				"key-grants: repo: serviceAccount:encrypter-only is not decrypter of key " + functionKey,
+				"key-grants: repo: serviceAccount:none is not encrypter and decrypter of key " + functionKey,
+			},
+		},
+		{
+			name:  "rotation and protection level",
+			usage: Usage{Resource: "tbl_test", Kind: "BigQuery table", Location: "us-west1", Key: bigqueryKey},
+			want: []string{
+				"key-rotation: tbl_test: key " + bigqueryKey + " rotates every 90d, expected 30d",
+				`key-protection-level: tbl_test: key ` + bigqueryKey + ` has protection level "SOFTWARE", expected HSM`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := Verify([]Usage{tt.usage}, describe, map[string]Expectation{"krg-secure-cloud-function": DefaultExpectation})
+			if !assert.NoError(t, err) {
+				return
+			}
+			var got []string
+			for _, f := range table.Findings() {
+				got = append(got, f.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	calls = 0
+	table, err := Verify([]Usage{
+		{Resource: "gcf-v2-sources", Kind: "Storage bucket", Location: "us-west1", Key: functionKey, ServiceAgents: []string{storageSA}},
+		{Resource: "repo", Kind: "Artifact Registry repository", Location: "us-west1", Key: functionKey},
+	}, describe, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, calls, "each key should be described once")
+		assert.Contains(t, table.String(), "gcf-v2-sources  Storage bucket                us-west1  krg-secure-cloud-function  key-secure-cloud-function  us-west1      30d       HSM         1/1     OK")
+	}
+
+	_, err = Verify([]Usage{{Resource: "repo", Key: "key-secure-cloud-function"}}, describe, nil)
+	assert.Error(t, err)
+}
+
+func TestExpectationsFromTerraform(t *testing.T) {
+	exps, err := ExpectationsFromTerraform("../../../examples/secure_cloud_function_bigquery_trigger/main.tf")
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]Expectation{
+			"krg-secure-artifact-registry": DefaultExpectation,
+			"krg-secure-bigquery":          {RotationPeriod: 30 * 24 * time.Hour, ProtectionLevel: "HSM"},
+			"krg-secure-cloud-function":    DefaultExpectation,
+		}, exps)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmek
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Expectation is the configuration of the keys of a key ring.
+type Expectation struct {
+	RotationPeriod  time.Duration
+	ProtectionLevel string
+}
+
+// DefaultExpectation is the default key_rotation_period and key_protection_level
+// of the secure-cloud-function and secure-cloud-function-security modules.
+var DefaultExpectation = Expectation{RotationPeriod: 30 * 24 * time.Hour, ProtectionLevel: "HSM"}
+
+// ExpectationsFromTerraform reads the key_rotation_period and key_protection_level
+// of the module calls in a Terraform file that create a key ring, keyed by the
+// key ring name. Unset values default to DefaultExpectation. Module calls whose
+// keyring or keyring_name is not a literal are ignored.
+func ExpectationsFromTerraform(path string) (map[string]Expectation, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	exps := map[string]Expectation{}
+	for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "module" {
+			continue
+		}
+		keyRing := literal(b, "keyring")
+		if keyRing == "" {
+			keyRing = literal(b, "keyring_name")
+		}
+		if keyRing == "" {
+			continue
+		}
+		e := DefaultExpectation
+		if period := literal(b, "key_rotation_period"); period != "" {
+			if e.RotationPeriod, err = time.ParseDuration(period); err != nil {
+				return nil, fmt.Errorf("%s: module %s: key_rotation_period: %w", path, b.Labels[0], err)
+			}
+		}
+		if level := literal(b, "key_protection_level"); level != "" {
+			e.ProtectionLevel = strings.ToUpper(level)
+		}
+		exps[keyRing] = e
+	}
+	return exps, nil
+}
+
+// literal returns the value of a string literal attribute, or "" when it is not set or not a literal.
+func literal(b *hclsyntax.Block, name string) string {
+	attr, ok := b.Body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return ""
+	}
+	v, err := convert.Convert(v, cty.String)
+	if err != nil || v.IsNull() {
+		return ""
+	}
+	return v.AsString()
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package insecure_cloud_function
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/negative"
+)
+
+// TestInsecureCloudFunction applies the organization policies of
+// secure-cloud-function-security to a dedicated project in the apply stage.
+// The verify stage then tries to create every insecure variant of a Cloud
+// Function in it and checks that the API rejects each of them for violating
+// the expected constraint. The teardown stage destroys the project.
+func TestInsecureCloudFunction(t *testing.T) {
+	setup := tft.NewTFBlueprintTest(t)
+	vars := map[string]interface{}{
+		"folder_id": setup.GetTFSetupStringOutput("folder_id"),
+	}
+	ict := tft.NewTFBlueprintTest(t, tft.WithVars(vars))
+
+	// The project, its organization policies and the VPC connector, without any function.
+	ict.DefineApply(func(assert *assert.Assertions) {
+		ict.DefaultApply(assert)
+	})
+	ict.DefineVerify(func(assert *assert.Assertions) {
+		for _, v := range negative.Variants {
+			t.Run(v.Name, func(t *testing.T) {
+				opts := ict.GetTFOptions()
+				opts.Vars = map[string]interface{}{"create_function": true}
+				maps.Copy(opts.Vars, vars)
+				maps.Copy(opts.Vars, v.Vars)
+				out, err := terraform.ApplyE(t, opts)
+				if err := negative.Rejected(v, out, err); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	})
+	ict.Test()
+}
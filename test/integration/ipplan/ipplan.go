@@ -0,0 +1,191 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ipplan validates the IP address plan of the secure-web-proxy module:
+// the connector subnet, the proxy-only subnet, the gateway addresses and the
+// private service access allocation.
+package ipplan
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+const (
+	CheckSyntax             = "syntax"
+	CheckConnectorSubnet    = "connector-subnet-size"
+	CheckGatewayAddress     = "gateway-address"
+	CheckProxySubnet        = "proxy-subnet-size"
+	CheckPSAPrefixLength    = "psa-prefix-length"
+	CheckOverlap            = "overlap"
+	connectorSubnetBits     = 28
+	maxProxySubnetBits      = 26
+	maxPSAPrefixLength      = 24
+	defaultPSAPrefixLength  = 16
+	reservedAddressesAtHead = 2
+	reservedAddressesAtTail = 2
+)
+
+// Plan is the address plan passed to the secure-web-proxy module.
+type Plan struct {
+	// SubnetRange is subnetwork_ip_range, the subnet of the VPC connector and of the gateway.
+	SubnetRange string
+	// ProxyRange is proxy_ip_range, the proxy-only subnet of the gateway.
+	ProxyRange string
+	// Addresses are the gateway addresses.
+	Addresses []string
+	// RestrictedAPIAddress is the address of the restricted Google APIs endpoint, such as 10.3.0.5.
+	RestrictedAPIAddress string
+	// PSAAddress is the first address of the private service access allocation.
+	// It is only known after apply and is not checked when empty.
+	PSAAddress string
+	// PSAPrefixLength is global_address_prefix_length. Zero means the module default of 16.
+	PSAPrefixLength int
+}
+
+// Conflict is a single problem of a Plan.
+type Conflict struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Check, c.Message)
+}
+
+type namedRange struct {
+	name   string
+	prefix netip.Prefix
+}
+
+// Validate returns every conflict of p.
+func Validate(p Plan) []Conflict {
+	var c []Conflict
+	add := func(check, format string, args ...any) {
+		c = append(c, Conflict{Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+	var ranges []namedRange
+
+	subnet, err := netip.ParsePrefix(p.SubnetRange)
+	if err != nil {
+		add(CheckSyntax, "subnet range %q is not a CIDR range", p.SubnetRange)
+	} else {
+		if subnet != subnet.Masked() {
+			add(CheckSyntax, "subnet range %s should be %s", subnet, subnet.Masked())
+		}
+		subnet = subnet.Masked()
+		if subnet.Bits() != connectorSubnetBits {
+			add(CheckConnectorSubnet, "subnet range %s is a /%d, the VPC connector requires a /%d", subnet, subnet.Bits(), connectorSubnetBits)
+		}
+		ranges = append(ranges, namedRange{"subnet range", subnet})
+	}
+
+	proxy, err := netip.ParsePrefix(p.ProxyRange)
+	if err != nil {
+		add(CheckSyntax, "proxy range %q is not a CIDR range", p.ProxyRange)
+	} else {
+		proxy = proxy.Masked()
+		if proxy.Bits() > maxProxySubnetBits {
+			add(CheckProxySubnet, "proxy range %s is a /%d, a proxy-only subnet must be a /%d or larger (a /23 is recommended)", proxy, proxy.Bits(), maxProxySubnetBits)
+		}
+		ranges = append(ranges, namedRange{"proxy range", proxy})
+	}
+
+	if len(p.Addresses) == 0 {
+		add(CheckGatewayAddress, "no gateway address")
+	}
+	for _, a := range p.Addresses {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			add(CheckSyntax, "gateway address %q is not an IP address", a)
+			continue
+		}
+		if subnet.IsValid() {
+			if !subnet.Contains(addr) {
+				add(CheckGatewayAddress, "gateway address %s is not inside subnet range %s", addr, subnet)
+			} else if reserved(subnet, addr) {
+				add(CheckGatewayAddress, "gateway address %s is one of the addresses Google Cloud reserves in subnet range %s", addr, subnet)
+			}
+		}
+		if proxy.IsValid() && proxy.Contains(addr) {
+			add(CheckOverlap, "gateway address %s is inside proxy range %s", addr, proxy)
+		}
+	}
+
+	if p.RestrictedAPIAddress != "" {
+		addr, err := netip.ParseAddr(p.RestrictedAPIAddress)
+		if err != nil {
+			add(CheckSyntax, "restricted API address %q is not an IP address", p.RestrictedAPIAddress)
+		} else {
+			ranges = append(ranges, namedRange{"restricted API address", netip.PrefixFrom(addr, addr.BitLen())})
+		}
+	}
+
+	psaBits := p.PSAPrefixLength
+	if psaBits == 0 {
+		psaBits = defaultPSAPrefixLength
+	}
+	if psaBits < 8 || psaBits > maxPSAPrefixLength {
+		add(CheckPSAPrefixLength, "global address prefix length /%d is outside /8 to /%d", psaBits, maxPSAPrefixLength)
+	} else if p.PSAAddress != "" {
+		addr, err := netip.ParseAddr(p.PSAAddress)
+		if err != nil {
+			add(CheckSyntax, "private service access address %q is not an IP address", p.PSAAddress)
+		} else {
+			psa := netip.PrefixFrom(addr, psaBits)
+			if psa.Masked().Addr() != addr {
+				add(CheckSyntax, "private service access address %s is not aligned to a /%d", addr, psaBits)
+			}
+			ranges = append(ranges, namedRange{"private service access allocation", psa.Masked()})
+		}
+	}
+
+	for i, a := range ranges {
+		for _, b := range ranges[i+1:] {
+			if a.prefix.Overlaps(b.prefix) {
+				add(CheckOverlap, "%s %s overlaps %s %s", a.name, a.prefix, b.name, b.prefix)
+			}
+		}
+	}
+	return c
+}
+
+// reserved reports whether addr is the network address, the default gateway,
+// the second-to-last address or the broadcast address of subnet.
+func reserved(subnet netip.Prefix, addr netip.Addr) bool {
+	first := subnet.Addr()
+	for i := 0; i < reservedAddressesAtHead; i++ {
+		if addr == first {
+			return true
+		}
+		first = first.Next()
+	}
+	last := lastAddr(subnet)
+	for i := 0; i < reservedAddressesAtTail; i++ {
+		if addr == last {
+			return true
+		}
+		last = last.Prev()
+	}
+	return false
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
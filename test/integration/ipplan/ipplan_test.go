@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipplan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		plan Plan
+		want []Conflict
+	}{
+		{
+			name: "bigquery trigger example",
+			plan: Plan{
+				SubnetRange:          "10.0.0.0/28",
+				ProxyRange:           "10.129.0.0/23",
+				Addresses:            []string{"10.0.0.10"},
+				RestrictedAPIAddress: "10.3.0.5",
+				PSAAddress:           "10.16.0.0",
+			},
+		},
+		{
+			name: "every conflict",
+			plan: Plan{
+				SubnetRange:          "10.0.0.0/24",
+				ProxyRange:           "10.0.0.0/27",
+				Addresses:            []string{"10.0.0.1", "10.0.1.10", "10.0.0.20"},
+				RestrictedAPIAddress: "10.0.0.5",
+				PSAAddress:           "10.0.0.0",
+				PSAPrefixLength:      20,
+			},
+			want: []Conflict{
+				{CheckConnectorSubnet, "subnet range 10.0.0.0/24 is a /24, the VPC connector requires a /28"},
+				{CheckProxySubnet, "proxy range 10.0.0.0/27 is a /27, a proxy-only subnet must be a /26 or larger (a /23 is recommended)"},
+				{CheckGatewayAddress, "gateway address 10.0.0.1 is one of the addresses Google Cloud reserves in subnet range 10.0.0.0/24"},
+				{CheckOverlap, "gateway address 10.0.0.1 is inside proxy range 10.0.0.0/27"},
+				{CheckGatewayAddress, "gateway address 10.0.1.10 is not inside subnet range 10.0.0.0/24"},
+				{CheckOverlap, "gateway address 10.0.0.20 is inside proxy range 10.0.0.0/27"},
+				{CheckOverlap, "subnet range 10.0.0.0/24 overlaps proxy range 10.0.0.0/27"},
+				{CheckOverlap, "subnet range 10.0.0.0/24 overlaps restricted API address 10.0.0.5/32"},
+				{CheckOverlap, "subnet range 10.0.0.0/24 overlaps private service access allocation 10.0.0.0/20"},
+				{CheckOverlap, "proxy range 10.0.0.0/27 overlaps restricted API address 10.0.0.5/32"},
+				{CheckOverlap, "proxy range 10.0.0.0/27 overlaps private service access allocation 10.0.0.0/20"},
+				{CheckOverlap, "restricted API address 10.0.0.5/32 overlaps private service access allocation 10.0.0.0/20"},
+			},
+		},
+		{
+			name: "syntax",
+			plan: Plan{
+				SubnetRange:          "10.0.0.1/28",
+				ProxyRange:           "10.129.0.0",
+				Addresses:            []string{"swp"},
+				RestrictedAPIAddress: "restricted",
+				PSAAddress:           "10.16.1.0",
+			},
+			want: []Conflict{
+				{CheckSyntax, "subnet range 10.0.0.1/28 should be 10.0.0.0/28"},
+				{CheckSyntax, `proxy range "10.129.0.0" is not a CIDR range`},
+				{CheckSyntax, `gateway address "swp" is not an IP address`},
+				{CheckSyntax, `restricted API address "restricted" is not an IP address`},
+				{CheckSyntax, "private service access address 10.16.1.0 is not aligned to a /16"},
+			},
+		},
+		{
+			name: "psa prefix length",
+			plan: Plan{
+				SubnetRange:     "10.0.0.0/28",
+				ProxyRange:      "10.129.0.0/23",
+				Addresses:       []string{"10.0.0.14"},
+				PSAPrefixLength: 28,
+			},
+			want: []Conflict{
+				{CheckGatewayAddress, "gateway address 10.0.0.14 is one of the addresses Google Cloud reserves in subnet range 10.0.0.0/28"},
+				{CheckPSAPrefixLength, "global address prefix length /28 is outside /8 to /24"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Validate(tt.plan))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package negative describes deliberately insecure variants of a Cloud Function
+// and checks that the organization policies of secure-cloud-function-security
+// deny each of them.
+package negative
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/orgpolicy"
+)
+
+// Variant is an insecure configuration of a Cloud Function.
+type Variant struct {
+	Name string
+	// Vars are the variables of test/fixtures/insecure_cloud_function that make it insecure.
+	Vars map[string]interface{}
+	// Constraint is the organization policy constraint that denies the variant.
+	Constraint string
+	// Value is the value a list Constraint must deny. It is empty for boolean constraints.
+	Value string
+}
+
+// Variants are the insecure configurations every change to the secure modules must keep denying.
+//
+// A function running as the Compute Engine default service account is not a
+// variant: no organization policy constraint denies it. The verify stage of
+// each secure example checks it with the dedicated-service-account rule of
+// the audit package instead.
+var Variants = []Variant{
+	{
+		Name:       "allow-all-ingress",
+		Vars:       map[string]interface{}{"ingress_settings": "ALLOW_ALL"},
+		Constraint: "constraints/cloudfunctions.allowedIngressSettings",
+		Value:      "ALLOW_ALL",
+	},
+	{
+		Name:       "private-ranges-egress",
+		Vars:       map[string]interface{}{"vpc_egress_value": "PRIVATE_RANGES_ONLY"},
+		Constraint: "constraints/cloudfunctions.allowedVpcConnectorEgressSettings",
+		Value:      "PRIVATE_RANGES_ONLY",
+	},
+	{
+		Name:       "no-vpc-connector",
+		Vars:       map[string]interface{}{"use_vpc_connector": false},
+		Constraint: "constraints/cloudfunctions.requireVPCConnector",
+	},
+}
+
+// Rejected returns an error unless err is a Terraform failure whose output
+// mentions the constraint that denies v.
+func Rejected(v Variant, output string, err error) error {
+	if err == nil {
+		return fmt.Errorf("%s was not rejected", v.Name)
+	}
+	if !strings.Contains(output, orgpolicy.ShortName(v.Constraint)) {
+		return fmt.Errorf("%s was rejected without mentioning %q: %v", v.Name, v.Constraint, err)
+	}
+	return nil
+}
+
+// CheckPolicies returns an error unless the organization policies in exps deny v.
+func CheckPolicies(v Variant, exps []orgpolicy.Expectation) error {
+	i := slices.IndexFunc(exps, func(e orgpolicy.Expectation) bool {
+		return orgpolicy.Constraint(e.Constraint) == orgpolicy.Constraint(v.Constraint)
+	})
+	if i < 0 {
+		return fmt.Errorf("%s: no organization policy sets %s", v.Name, v.Constraint)
+	}
+	e := exps[i]
+	switch e.Type {
+	case orgpolicy.TypeBoolean:
+		if !e.Enforce {
+			return fmt.Errorf("%s: %s is not enforced", v.Name, v.Constraint)
+		}
+	case orgpolicy.TypeList:
+		if slices.Contains(e.Deny, v.Value) {
+			return nil
+		}
+		if len(e.Allow) == 0 || slices.Contains(e.Allow, v.Value) {
+			return fmt.Errorf("%s: %s allows %s", v.Name, v.Constraint, v.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/orgpolicy"
+)
+
+const orgPolicies = "../../../modules/secure-cloud-function-security/org_policies.tf"
+
+// TestVariantsDeniedByOrgPolicies fails when secure-cloud-function-security stops denying a variant.
+func TestVariantsDeniedByOrgPolicies(t *testing.T) {
+	exps, err := orgpolicy.ExpectationsFromTerraform(orgPolicies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			assert.NoError(t, CheckPolicies(v, exps))
+		})
+	}
+}
+
+func TestCheckPolicies(t *testing.T) {
+	ingress := Variant{Name: "ingress", Constraint: "cloudfunctions.allowedIngressSettings", Value: "ALLOW_ALL"}
+	connector := Variant{Name: "connector", Constraint: "constraints/cloudfunctions.requireVPCConnector"}
+	tests := []struct {
+		name    string
+		variant Variant
+		exps    []orgpolicy.Expectation
+		wantErr string
+	}{
+		{
+			name:    "allow list without the value",
+			variant: ingress,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.allowedIngressSettings", Type: orgpolicy.TypeList, Allow: []string{"ALLOW_INTERNAL_ONLY"}}},
+		},
+		{
+			name:    "deny list with the value",
+			variant: ingress,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.allowedIngressSettings", Type: orgpolicy.TypeList, Deny: []string{"ALLOW_ALL"}}},
+		},
+		{
+			name:    "allow list with the value",
+			variant: ingress,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.allowedIngressSettings", Type: orgpolicy.TypeList, Allow: []string{"ALLOW_INTERNAL_ONLY", "ALLOW_ALL"}}},
+			wantErr: "ingress: cloudfunctions.allowedIngressSettings allows ALLOW_ALL",
+		},
+		{
+			name:    "unrestricted list",
+			variant: ingress,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.allowedIngressSettings", Type: orgpolicy.TypeList}},
+			wantErr: "allows ALLOW_ALL",
+		},
+		{
+			name:    "enforced boolean",
+			variant: connector,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: orgpolicy.TypeBoolean, Enforce: true}},
+		},
+		{
+			name:    "boolean not enforced",
+			variant: connector,
+			exps:    []orgpolicy.Expectation{{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: orgpolicy.TypeBoolean}},
+			wantErr: "connector: constraints/cloudfunctions.requireVPCConnector is not enforced",
+		},
+		{
+			name:    "missing policy",
+			variant: connector,
+			wantErr: "connector: no organization policy sets constraints/cloudfunctions.requireVPCConnector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPolicies(tt.variant, tt.exps)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRejected(t *testing.T) {
+	v := Variants[0]
+	failed := errors.New("exit status 1")
+	tests := []struct {
+		name    string
+		output  string
+		err     error
+		wantErr string
+	}{
+		{
+			name:   "org policy violation",
+			output: "Error: Error creating function: googleapi: Error 400: Constraint constraints/cloudfunctions.allowedIngressSettings violated for projects/prj-test attempting to create a function with ingress setting ALLOW_ALL.",
+			err:    failed,
+		},
+		{
+			name:   "violation without the constraints prefix",
+			output: "Error: Error creating function: googleapi: Error 400: Constraint cloudfunctions.allowedIngressSettings violated for projects/prj-test.",
+			err:    failed,
+		},
+		{
+			name:    "applied",
+			output:  "Apply complete! Resources: 12 added, 0 changed, 0 destroyed.",
+			wantErr: "allow-all-ingress was not rejected",
+		},
+		{
+			name:    "other error",
+			output:  "Error: googleapi: Error 403: Permission denied on resource project prj-test.",
+			err:     failed,
+			wantErr: `allow-all-ingress was rejected without mentioning "constraints/cloudfunctions.allowedIngressSettings": exit status 1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Rejected(v, tt.output, tt.err)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
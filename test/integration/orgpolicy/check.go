@@ -0,0 +1,120 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orgpolicy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expectation is a constraint a resource must enforce.
+type Expectation struct {
+	Constraint string
+	Type       string
+	// Allow are the only values a list policy may allow.
+	Allow []string
+	// Deny are values a list policy must deny.
+	Deny []string
+	// Enforce is the expected value of a boolean policy.
+	Enforce bool
+}
+
+// Finding is a single difference between a policy and its Expectation.
+type Finding struct {
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Constraint, f.Message)
+}
+
+// Report is the result of checking every expected constraint of a resource.
+type Report struct {
+	// Resource is the resource the policies were read from, such as projects/my-project.
+	Resource    string    `json:"resource"`
+	Constraints []string  `json:"constraints"`
+	Findings    []Finding `json:"findings"`
+}
+
+// OK reports whether every constraint is enforced as expected.
+func (r Report) OK() bool {
+	return len(r.Findings) == 0
+}
+
+// Check returns the findings of policy p against e. p should be the effective policy.
+func Check(e Expectation, p Policy) []Finding {
+	var f []Finding
+	add := func(format string, args ...any) {
+		f = append(f, Finding{Constraint: Constraint(e.Constraint), Message: fmt.Sprintf(format, args...)})
+	}
+	if p.Type == "" {
+		add("no policy is set")
+		return f
+	}
+	if p.Type != e.Type {
+		add("is a %s policy, expected a %s policy", p.Type, e.Type)
+		return f
+	}
+	if e.Type == TypeBoolean {
+		if p.Enforced != e.Enforce {
+			add("enforced is %t, expected %t", p.Enforced, e.Enforce)
+		}
+		return f
+	}
+
+	for _, v := range e.Allow {
+		if !p.Allows(v) {
+			add("does not allow %s", v)
+		}
+	}
+	if len(e.Allow) > 0 {
+		switch {
+		case p.AllValues == AllValuesAllow:
+			add("allows all values, expected only %s", strings.Join(e.Allow, ", "))
+		case p.AllValues == "" && len(p.AllowedValues) == 0:
+			add("does not restrict the allowed values, expected only %s", strings.Join(e.Allow, ", "))
+		}
+		for _, v := range p.AllowedValues {
+			if !contains(e.Allow, v) && !contains(p.DeniedValues, v) {
+				add("allows %s, expected only %s", v, strings.Join(e.Allow, ", "))
+			}
+		}
+	}
+	for _, v := range e.Deny {
+		if p.Allows(v) {
+			add("does not deny %s", v)
+		}
+	}
+	return f
+}
+
+// Verify checks every expectation against the policy returned by describe and
+// aggregates the results in one Report. describe receives the constraint with the
+// constraints/ prefix and should return the effective policy.
+func Verify(resource string, exps []Expectation, describe func(constraint string) (Policy, error)) Report {
+	r := Report{Resource: resource}
+	for _, e := range exps {
+		c := Constraint(e.Constraint)
+		r.Constraints = append(r.Constraints, c)
+		p, err := describe(c)
+		if err != nil {
+			r.Findings = append(r.Findings, Finding{Constraint: c, Message: fmt.Sprintf("error reading policy: %v", err)})
+			continue
+		}
+		r.Findings = append(r.Findings, Check(e, p)...)
+	}
+	return r
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package orgpolicy checks organization policies against the constraints a
+// blueprint is expected to enforce. It reads both the Resource Manager v1
+// format (gcloud resource-manager org-policies) and the Organization Policy v2
+// format (gcloud org-policies).
+package orgpolicy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	TypeList       = "list"
+	TypeBoolean    = "boolean"
+	AllValuesAllow = "ALLOW"
+	AllValuesDeny  = "DENY"
+	constraintPath = "constraints/"
+	valuePrefix    = "is:"
+)
+
+// Policy is an organization policy set on a resource, in a format independent
+// of the API version it was read from.
+type Policy struct {
+	// Constraint always has the constraints/ prefix.
+	Constraint string
+	// Type is TypeList, TypeBoolean or empty when the resource sets no policy.
+	Type string
+	// Enforced is the value of a boolean policy.
+	Enforced bool
+	// AllValues is AllValuesAllow or AllValuesDeny when a list policy allows or denies every value.
+	AllValues     string
+	AllowedValues []string
+	DeniedValues  []string
+	// InheritFromParent merges a list policy with the policy of the parent resource.
+	InheritFromParent bool
+	// Reset restores the default of the constraint, ignoring the parent resources.
+	Reset bool
+}
+
+// Constraint returns name with the constraints/ prefix. Both forms are accepted by gcloud.
+func Constraint(name string) string {
+	if strings.HasPrefix(name, constraintPath) {
+		return name
+	}
+	return constraintPath + name
+}
+
+// ShortName returns name without the constraints/ prefix, as the v2 API expects it.
+func ShortName(name string) string {
+	return strings.TrimPrefix(name, constraintPath)
+}
+
+// Parse decodes the JSON output of either
+// "gcloud resource-manager org-policies describe" or "gcloud org-policies describe".
+// Rules of v2 policies with a condition are not evaluated.
+func Parse(data []byte) (Policy, error) {
+	if !gjson.ValidBytes(data) {
+		return Policy{}, fmt.Errorf("org policy is not valid JSON")
+	}
+	r := gjson.ParseBytes(data)
+	if r.IsArray() {
+		// gcloud returns a list when --flatten is used.
+		r = r.Get("0")
+	}
+	switch {
+	case r.Get("constraint").Exists():
+		return parseV1(r), nil
+	case r.Get("name").Exists():
+		return parseV2(r)
+	}
+	return Policy{}, fmt.Errorf("org policy has neither a constraint nor a name")
+}
+
+func parseV1(r gjson.Result) Policy {
+	p := Policy{Constraint: Constraint(r.Get("constraint").String())}
+	switch {
+	case r.Get("restoreDefault").Exists():
+		p.Reset = true
+	case r.Get("booleanPolicy").Exists():
+		p.Type = TypeBoolean
+		p.Enforced = r.Get("booleanPolicy.enforced").Bool()
+	case r.Get("listPolicy").Exists():
+		l := r.Get("listPolicy")
+		p.Type = TypeList
+		p.AllValues = l.Get("allValues").String()
+		p.AllowedValues = stringSlice(l.Get("allowedValues"))
+		p.DeniedValues = stringSlice(l.Get("deniedValues"))
+		p.InheritFromParent = l.Get("inheritFromParent").Bool()
+	}
+	return p
+}
+
+func parseV2(r gjson.Result) (Policy, error) {
+	name := r.Get("name").String()
+	i := strings.LastIndex(name, "/policies/")
+	if i < 0 {
+		return Policy{}, fmt.Errorf("org policy name %q is not a policy resource name", name)
+	}
+	p := Policy{Constraint: Constraint(name[i+len("/policies/"):])}
+	spec := r.Get("spec")
+	p.Reset = spec.Get("reset").Bool()
+	p.InheritFromParent = spec.Get("inheritFromParent").Bool()
+	for _, rule := range spec.Get("rules").Array() {
+		if rule.Get("condition").Exists() {
+			continue
+		}
+		switch {
+		case rule.Get("enforce").Exists():
+			p.Type = TypeBoolean
+			p.Enforced = rule.Get("enforce").Bool()
+		case rule.Get("allowAll").Bool():
+			p.Type = TypeList
+			p.AllValues = AllValuesAllow
+		case rule.Get("denyAll").Bool():
+			p.Type = TypeList
+			p.AllValues = AllValuesDeny
+		case rule.Get("values").Exists():
+			p.Type = TypeList
+			p.AllowedValues = append(p.AllowedValues, stringSlice(rule.Get("values.allowedValues"))...)
+			p.DeniedValues = append(p.DeniedValues, stringSlice(rule.Get("values.deniedValues"))...)
+		}
+	}
+	if p.Type != TypeList {
+		p.InheritFromParent = false
+	}
+	return p, nil
+}
+
+func stringSlice(r gjson.Result) []string {
+	var s []string
+	for _, v := range r.Array() {
+		s = append(s, v.String())
+	}
+	return s
+}
+
+// Effective returns the policy in effect on a resource, given the policies set on
+// the resource and on each of its ancestors, from the resource up to the organization.
+func Effective(chain ...Policy) Policy {
+	if len(chain) == 0 {
+		return Policy{}
+	}
+	p := chain[0]
+	switch {
+	case p.Reset:
+		return Policy{Constraint: p.Constraint}
+	case p.Type == "":
+		parent := Effective(chain[1:]...)
+		if parent.Constraint == "" {
+			parent.Constraint = p.Constraint
+		}
+		return parent
+	case p.Type == TypeList && p.InheritFromParent && p.AllValues == "":
+		parent := Effective(chain[1:]...)
+		if parent.Type != TypeList {
+			p.InheritFromParent = false
+			return p
+		}
+		merged := Policy{
+			Constraint:    p.Constraint,
+			Type:          TypeList,
+			AllowedValues: union(parent.AllowedValues, p.AllowedValues),
+			DeniedValues:  union(parent.DeniedValues, p.DeniedValues),
+			// Values listed by the resource don't narrow a parent that allows or denies every value.
+			AllValues: parent.AllValues,
+		}
+		return merged
+	}
+	p.InheritFromParent = false
+	return p
+}
+
+func union(a, b []string) []string {
+	u := slices.Clone(a)
+	for _, v := range b {
+		if !contains(u, v) {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+// contains compares values with and without the is: prefix.
+func contains(values []string, v string) bool {
+	return slices.ContainsFunc(values, func(s string) bool {
+		return strings.TrimPrefix(s, valuePrefix) == strings.TrimPrefix(v, valuePrefix)
+	})
+}
+
+// Allows reports whether a list policy allows value.
+func (p Policy) Allows(value string) bool {
+	if p.AllValues == AllValuesDeny || contains(p.DeniedValues, value) {
+		return false
+	}
+	if p.AllValues == AllValuesAllow || len(p.AllowedValues) == 0 {
+		return true
+	}
+	return contains(p.AllowedValues, value)
+}
@@ -0,0 +1,322 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orgpolicy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Policy
+		wantErr string
+	}{
+		{
+			name: "v1 list",
+			data: `{"constraint": "constraints/run.allowedIngress", "listPolicy": {"allowedValues": ["is:internal-and-cloud-load-balancing"], "inheritFromParent": true}}`,
+			want: Policy{Constraint: "constraints/run.allowedIngress", Type: TypeList, AllowedValues: []string{"is:internal-and-cloud-load-balancing"}, InheritFromParent: true},
+		},
+		{
+			name: "v1 flattened list",
+			data: `[{"constraint": "cloudfunctions.allowedVpcConnectorEgressSettings", "listPolicy": {"allowedValues": "ALL_TRAFFIC"}}]`,
+			want: Policy{Constraint: "constraints/cloudfunctions.allowedVpcConnectorEgressSettings", Type: TypeList, AllowedValues: []string{"ALL_TRAFFIC"}},
+		},
+		{
+			name: "v1 deny all",
+			data: `{"constraint": "constraints/gcp.resourceLocations", "listPolicy": {"allValues": "DENY"}}`,
+			want: Policy{Constraint: "constraints/gcp.resourceLocations", Type: TypeList, AllValues: AllValuesDeny},
+		},
+		{
+			name: "v1 boolean",
+			data: `{"constraint": "constraints/cloudfunctions.requireVPCConnector", "booleanPolicy": {"enforced": true}}`,
+			want: Policy{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: TypeBoolean, Enforced: true},
+		},
+		{
+			name: "v1 restore default",
+			data: `{"constraint": "constraints/cloudfunctions.requireVPCConnector", "restoreDefault": {}}`,
+			want: Policy{Constraint: "constraints/cloudfunctions.requireVPCConnector", Reset: true},
+		},
+		{
+			name: "v1 unset",
+			data: `{"constraint": "constraints/cloudfunctions.requireVPCConnector", "etag": "BwYSm8bqH5E="}`,
+			want: Policy{Constraint: "constraints/cloudfunctions.requireVPCConnector"},
+		},
+		{
+			name: "v2 list",
+			data: `{"name": "projects/123/policies/run.allowedVPCEgress", "spec": {"inheritFromParent": true, "rules": [
+				{"values": {"allowedValues": ["all-traffic"]}},
+				{"values": {"deniedValues": ["private-ranges-only"]}},
+				{"condition": {"expression": "resource.matchTag('123/env', 'dev')"}, "allowAll": true}]}}`,
+			want: Policy{Constraint: "constraints/run.allowedVPCEgress", Type: TypeList, AllowedValues: []string{"all-traffic"}, DeniedValues: []string{"private-ranges-only"}, InheritFromParent: true},
+		},
+		{
+			name: "v2 allow all",
+			data: `{"name": "folders/456/policies/run.allowedIngress", "spec": {"rules": [{"allowAll": true}]}}`,
+			want: Policy{Constraint: "constraints/run.allowedIngress", Type: TypeList, AllValues: AllValuesAllow},
+		},
+		{
+			name: "v2 boolean",
+			data: `{"name": "organizations/789/policies/cloudfunctions.requireVPCConnector", "spec": {"inheritFromParent": true, "rules": [{"enforce": false}]}}`,
+			want: Policy{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: TypeBoolean},
+		},
+		{
+			name: "v2 reset",
+			data: `{"name": "projects/123/policies/cloudfunctions.requireVPCConnector", "spec": {"reset": true}}`,
+			want: Policy{Constraint: "constraints/cloudfunctions.requireVPCConnector", Reset: true},
+		},
+		{name: "invalid", data: `{`, wantErr: "org policy is not valid JSON"},
+		{name: "unknown", data: `{"etag": "x"}`, wantErr: "org policy has neither a constraint nor a name"},
+		{name: "bad name", data: `{"name": "run.allowedIngress"}`, wantErr: `org policy name "run.allowedIngress" is not a policy resource name`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	const c = "constraints/run.allowedVPCEgress"
+	org := Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"private-ranges-only"}, DeniedValues: []string{"none"}}
+	tests := []struct {
+		name  string
+		chain []Policy
+		want  Policy
+	}{
+		{
+			name:  "unset inherits",
+			chain: []Policy{{Constraint: c}, {Constraint: c}, org},
+			want:  org,
+		},
+		{
+			name:  "merge",
+			chain: []Policy{{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, InheritFromParent: true}, org},
+			want:  Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"private-ranges-only", "all-traffic"}, DeniedValues: []string{"none"}},
+		},
+		{
+			name: "merge with parent allowing all values",
+			chain: []Policy{
+				{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, InheritFromParent: true},
+				{Constraint: c, Type: TypeList, AllValues: AllValuesAllow},
+			},
+			want: Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, AllValues: AllValuesAllow},
+		},
+		{
+			name: "merge with parent denying all values",
+			chain: []Policy{
+				{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, InheritFromParent: true},
+				{Constraint: c, Type: TypeList, AllValues: AllValuesDeny},
+			},
+			want: Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, AllValues: AllValuesDeny},
+		},
+		{
+			name:  "override",
+			chain: []Policy{{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}}, org},
+			want:  Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}},
+		},
+		{
+			name:  "inherit without parent",
+			chain: []Policy{{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, InheritFromParent: true}, {Constraint: c}},
+			want:  Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}},
+		},
+		{
+			name:  "reset",
+			chain: []Policy{{Constraint: c}, {Constraint: c, Reset: true}, org},
+			want:  Policy{Constraint: c},
+		},
+		{
+			name: "no policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Effective(tt.chain...))
+		})
+	}
+}
+
+func TestEffectiveAllows(t *testing.T) {
+	const c = "constraints/run.allowedVPCEgress"
+	child := Policy{Constraint: c, Type: TypeList, AllowedValues: []string{"all-traffic"}, InheritFromParent: true}
+	allowAll := Effective(child, Policy{Constraint: c, Type: TypeList, AllValues: AllValuesAllow})
+	assert.True(t, allowAll.Allows("private-ranges-only"), "a parent allowing all values still allows unlisted values")
+	assert.True(t, allowAll.Allows("all-traffic"))
+	denyAll := Effective(child, Policy{Constraint: c, Type: TypeList, AllValues: AllValuesDeny})
+	assert.False(t, denyAll.Allows("all-traffic"), "a parent denying all values denies the listed values too")
+}
+
+func TestCheck(t *testing.T) {
+	ingress := Expectation{Constraint: "run.allowedIngress", Type: TypeList, Allow: []string{"is:internal-and-cloud-load-balancing"}}
+	locations := Expectation{Constraint: "constraints/gcp.resourceLocations", Type: TypeList, Deny: []string{"in:us-locations"}}
+	vpc := Expectation{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: TypeBoolean, Enforce: true}
+	tests := []struct {
+		name   string
+		exp    Expectation
+		policy Policy
+		want   []string
+	}{
+		{
+			name:   "allowed",
+			exp:    ingress,
+			policy: Policy{Type: TypeList, AllowedValues: []string{"internal-and-cloud-load-balancing"}},
+		},
+		{
+			name:   "extra value",
+			exp:    ingress,
+			policy: Policy{Type: TypeList, AllowedValues: []string{"is:internal-and-cloud-load-balancing", "all"}},
+			want:   []string{"constraints/run.allowedIngress: allows all, expected only is:internal-and-cloud-load-balancing"},
+		},
+		{
+			name:   "allow all",
+			exp:    ingress,
+			policy: Policy{Type: TypeList, AllValues: AllValuesAllow},
+			want:   []string{"constraints/run.allowedIngress: allows all values, expected only is:internal-and-cloud-load-balancing"},
+		},
+		{
+			name:   "unrestricted",
+			exp:    ingress,
+			policy: Policy{Type: TypeList, DeniedValues: []string{"all"}},
+			want:   []string{"constraints/run.allowedIngress: does not restrict the allowed values, expected only is:internal-and-cloud-load-balancing"},
+		},
+		{
+			name:   "denied",
+			exp:    ingress,
+			policy: Policy{Type: TypeList, AllowedValues: []string{"is:internal-and-cloud-load-balancing"}, DeniedValues: []string{"internal-and-cloud-load-balancing"}},
+			want:   []string{"constraints/run.allowedIngress: does not allow is:internal-and-cloud-load-balancing"},
+		},
+		{
+			name:   "deny value",
+			exp:    locations,
+			policy: Policy{Type: TypeList, DeniedValues: []string{"in:us-locations"}},
+		},
+		{
+			name:   "deny value missing",
+			exp:    locations,
+			policy: Policy{Type: TypeList, DeniedValues: []string{"in:eu-locations"}},
+			want:   []string{"constraints/gcp.resourceLocations: does not deny in:us-locations"},
+		},
+		{
+			name:   "enforced",
+			exp:    vpc,
+			policy: Policy{Type: TypeBoolean, Enforced: true},
+		},
+		{
+			name:   "not enforced",
+			exp:    vpc,
+			policy: Policy{Type: TypeBoolean},
+			want:   []string{"constraints/cloudfunctions.requireVPCConnector: enforced is false, expected true"},
+		},
+		{
+			name:   "wrong type",
+			exp:    vpc,
+			policy: Policy{Type: TypeList, AllValues: AllValuesDeny},
+			want:   []string{"constraints/cloudfunctions.requireVPCConnector: is a list policy, expected a boolean policy"},
+		},
+		{
+			name: "unset",
+			exp:  vpc,
+			want: []string{"constraints/cloudfunctions.requireVPCConnector: no policy is set"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Check(tt.exp, tt.policy) {
+				got = append(got, f.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	exps, err := ExpectationsFromTerraform("../../../modules/secure-cloud-function-security/org_policies.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Expectation{
+		{Constraint: "constraints/cloudfunctions.allowedIngressSettings", Type: TypeList, Allow: []string{"ALLOW_INTERNAL_ONLY"}},
+		{Constraint: "constraints/cloudfunctions.requireVPCConnector", Type: TypeBoolean, Enforce: true},
+		{Constraint: "constraints/cloudfunctions.allowedVpcConnectorEgressSettings", Type: TypeList, Allow: []string{"ALL_TRAFFIC"}},
+		{Constraint: "constraints/run.allowedIngress", Type: TypeList, Allow: []string{"is:internal-and-cloud-load-balancing"}},
+		{Constraint: "constraints/run.allowedVPCEgress", Type: TypeList, Allow: []string{"all-traffic"}},
+	}, exps)
+
+	policies := map[string]string{
+		"constraints/cloudfunctions.allowedIngressSettings": `{"name": "projects/123/policies/cloudfunctions.allowedIngressSettings", "spec": {"rules": [{"values": {"allowedValues": ["ALLOW_INTERNAL_ONLY"]}}]}}`,
+		"constraints/cloudfunctions.requireVPCConnector":    `{"constraint": "constraints/cloudfunctions.requireVPCConnector", "booleanPolicy": {}}`,
+		"constraints/run.allowedIngress":                    `{"name": "projects/123/policies/run.allowedIngress", "spec": {"rules": [{"allowAll": true}]}}`,
+		"constraints/run.allowedVPCEgress":                  `{"name": "projects/123/policies/run.allowedVPCEgress", "spec": {"rules": [{"values": {"allowedValues": ["all-traffic"]}}]}}`,
+	}
+	r := Verify("projects/prj", exps, func(constraint string) (Policy, error) {
+		data, ok := policies[constraint]
+		if !ok {
+			return Policy{}, fmt.Errorf("NOT_FOUND")
+		}
+		return Parse([]byte(data))
+	})
+	assert.False(t, r.OK())
+	assert.Equal(t, "projects/prj", r.Resource)
+	assert.Len(t, r.Constraints, 5)
+	assert.Equal(t, []Finding{
+		{"constraints/cloudfunctions.requireVPCConnector", "enforced is false, expected true"},
+		{"constraints/cloudfunctions.allowedVpcConnectorEgressSettings", "error reading policy: NOT_FOUND"},
+		{"constraints/run.allowedIngress", "allows all values, expected only is:internal-and-cloud-load-balancing"},
+	}, r.Findings)
+}
+
+func TestExpectationsFromTerraformErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, wantErr string
+	}{
+		{"no module", `module "x" { source = "./x" }`, "main.tf: no module call with source terraform-google-modules/org-policy/google"},
+		{"no constraint", `module "x" { source = "terraform-google-modules/org-policy/google" }`, "main.tf: module x has no constraint"},
+		{"variable", `module "x" {
+  source     = "terraform-google-modules/org-policy/google"
+  constraint = "run.allowedIngress"
+  allow      = var.allow
+}`, "main.tf: module x: allow is not a literal"},
+		{"policy type", `module "x" {
+  source      = "terraform-google-modules/org-policy/google"
+  constraint  = "run.allowedIngress"
+  policy_type = "map"
+}`, `main.tf: module x has unknown policy_type "map"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/main.tf"
+			if err := os.WriteFile(path, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ExpectationsFromTerraform(path)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.wantErr, strings.TrimPrefix(err.Error(), filepath.Dir(path)+"/"))
+			}
+		})
+	}
+}
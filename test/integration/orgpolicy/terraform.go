@@ -0,0 +1,125 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orgpolicy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+const moduleSource = "terraform-google-modules/org-policy/google"
+
+// ExpectationsFromTerraform reads the constraints enforced by the org-policy module
+// calls in a Terraform file, such as modules/secure-cloud-function-security/org_policies.tf.
+// constraint, policy_type, allow, deny and enforce must be literals.
+func ExpectationsFromTerraform(path string) ([]Expectation, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var exps []Expectation
+	for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "module" {
+			continue
+		}
+		source, err := attribute(b, "source", cty.String)
+		if err != nil || source.IsNull() || !strings.HasSuffix(source.AsString(), moduleSource) {
+			continue
+		}
+		name := fmt.Sprintf("%s: module %s", path, b.Labels[0])
+
+		constraint, err := attribute(b, "constraint", cty.String)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if constraint.IsNull() {
+			return nil, fmt.Errorf("%s has no constraint", name)
+		}
+		e := Expectation{Constraint: Constraint(constraint.AsString()), Type: TypeList}
+
+		policyType, err := attribute(b, "policy_type", cty.String)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if !policyType.IsNull() {
+			e.Type = policyType.AsString()
+		}
+		switch e.Type {
+		case TypeBoolean:
+			enforce, err := attribute(b, "enforce", cty.Bool)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			e.Enforce = enforce.IsNull() || enforce.True()
+		case TypeList:
+			if e.Allow, err = listAttribute(b, "allow"); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if e.Deny, err = listAttribute(b, "deny"); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s has unknown policy_type %q", name, e.Type)
+		}
+		exps = append(exps, e)
+	}
+	if len(exps) == 0 {
+		return nil, fmt.Errorf("%s: no module call with source %s", path, moduleSource)
+	}
+	return exps, nil
+}
+
+// attribute returns the literal value of an attribute converted to ty, or a null value when it is not set.
+func attribute(b *hclsyntax.Block, name string, ty cty.Type) (cty.Value, error) {
+	attr, ok := b.Body.Attributes[name]
+	if !ok {
+		return cty.NullVal(ty), nil
+	}
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%s is not a literal", name)
+	}
+	v, err := convert.Convert(v, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+func listAttribute(b *hclsyntax.Block, name string) ([]string, error) {
+	v, err := attribute(b, name, cty.List(cty.String))
+	if err != nil || v.IsNull() {
+		return nil, err
+	}
+	var values []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, e := it.Element()
+		if e.IsNull() {
+			return nil, fmt.Errorf("%s must only contain strings", name)
+		}
+		values = append(values, e.AsString())
+	}
+	return values, nil
+}
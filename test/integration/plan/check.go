@@ -0,0 +1,161 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	CheckFunctionSource         = "function-source"
+	CheckEgressWithoutConnector = "egress-without-connector"
+	CheckFirewallLogging        = "firewall-logging"
+	CheckFirewallOpenIngress    = "firewall-open-ingress"
+	CheckKMSRotation            = "kms-rotation"
+	CheckIAMPublicMember        = "iam-public-member"
+	CheckIAMPrimitiveRole       = "iam-primitive-role"
+	// MaxKeyRotationPeriod is the longest rotation period accepted for a crypto key.
+	MaxKeyRotationPeriod = 90 * 24 * time.Hour
+)
+
+var (
+	publicMembers  = []string{"allUsers", "allAuthenticatedUsers"}
+	primitiveRoles = []string{"roles/owner", "roles/editor", "roles/viewer"}
+)
+
+// Finding is a planned resource that does not match the expected module behaviour.
+type Finding struct {
+	Check   string `json:"check"`
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Check, f.Address, f.Message)
+}
+
+// Verify runs every check on p.
+func Verify(p *Plan) []Finding {
+	var f []Finding
+	for _, check := range []func(*Plan) []Finding{
+		CheckFunctions,
+		CheckFirewalls,
+		CheckCryptoKeys,
+		CheckIAM,
+	} {
+		f = append(f, check(p)...)
+	}
+	return f
+}
+
+// CheckFunctions checks that each google_cloudfunctions2_function has exactly one
+// build source and sets no egress settings without a VPC connector.
+func CheckFunctions(p *Plan) []Finding {
+	var f []Finding
+	for _, r := range p.Resources("google_cloudfunctions2_function") {
+		source := r.Values.Get("build_config.0.source.0")
+		storage := len(source.Get("storage_source").Array())
+		repo := len(source.Get("repo_source").Array())
+		if storage+repo != 1 {
+			f = append(f, Finding{CheckFunctionSource, r.Address, fmt.Sprintf("build source has %d storage_source and %d repo_source blocks, expected exactly one", storage, repo)})
+		}
+
+		sc := r.Values.Get("service_config.0")
+		connector := sc.Get("vpc_connector")
+		egress := sc.Get("vpc_connector_egress_settings")
+		// Unknown values are missing from the plan, only a null connector is known to be unset.
+		if connector.Exists() && isNull(connector) && egress.Exists() && !isNull(egress) {
+			f = append(f, Finding{CheckEgressWithoutConnector, r.Address, fmt.Sprintf("vpc_connector_egress_settings is %s without a vpc_connector", egress.String())})
+		}
+	}
+	return f
+}
+
+// CheckFirewalls checks that each google_compute_firewall logs and does not allow ingress from the internet.
+func CheckFirewalls(p *Plan) []Finding {
+	var f []Finding
+	for _, r := range p.Resources("google_compute_firewall") {
+		if len(r.Values.Get("log_config").Array()) == 0 {
+			f = append(f, Finding{CheckFirewallLogging, r.Address, "logging is disabled"})
+		}
+		direction := r.Values.Get("direction").String()
+		if direction != "" && direction != "INGRESS" || len(r.Values.Get("allow").Array()) == 0 {
+			continue
+		}
+		for _, s := range r.Values.Get("source_ranges").Array() {
+			if s.String() == "0.0.0.0/0" || s.String() == "::/0" {
+				f = append(f, Finding{CheckFirewallOpenIngress, r.Address, fmt.Sprintf("allows ingress from %s", s.String())})
+			}
+		}
+	}
+	return f
+}
+
+// CheckCryptoKeys checks that each google_kms_crypto_key rotates at least every MaxKeyRotationPeriod.
+func CheckCryptoKeys(p *Plan) []Finding {
+	var f []Finding
+	for _, r := range p.Resources("google_kms_crypto_key") {
+		period := r.Values.Get("rotation_period")
+		if !period.Exists() {
+			continue
+		}
+		if isNull(period) {
+			f = append(f, Finding{CheckKMSRotation, r.Address, "rotation_period is not set"})
+			continue
+		}
+		d, err := time.ParseDuration(period.String())
+		if err != nil {
+			f = append(f, Finding{CheckKMSRotation, r.Address, fmt.Sprintf("rotation_period %q is not a duration", period.String())})
+			continue
+		}
+		if d > MaxKeyRotationPeriod {
+			f = append(f, Finding{CheckKMSRotation, r.Address, fmt.Sprintf("rotation_period %s is longer than %.0fs", period.String(), MaxKeyRotationPeriod.Seconds())})
+		}
+	}
+	return f
+}
+
+// CheckIAM checks that no IAM member or binding grants a primitive role or grants access to everyone.
+func CheckIAM(p *Plan) []Finding {
+	var f []Finding
+	for _, r := range p.Resources() {
+		if !strings.HasSuffix(r.Type, "_iam_member") && !strings.HasSuffix(r.Type, "_iam_binding") {
+			continue
+		}
+		role := r.Values.Get("role").String()
+		if slices.Contains(primitiveRoles, role) {
+			f = append(f, Finding{CheckIAMPrimitiveRole, r.Address, fmt.Sprintf("grants primitive role %s", role)})
+		}
+		members := r.Values.Get("members").Array()
+		if m := r.Values.Get("member"); m.Exists() {
+			members = append(members, m)
+		}
+		for _, m := range members {
+			if slices.Contains(publicMembers, m.String()) {
+				f = append(f, Finding{CheckIAMPublicMember, r.Address, fmt.Sprintf("grants %s to %s", role, m.String())})
+			}
+		}
+	}
+	return f
+}
+
+func isNull(r gjson.Result) bool {
+	return r.Type == gjson.Null
+}
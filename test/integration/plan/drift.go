@@ -0,0 +1,210 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
+	"github.com/stretchr/testify/assert"
+)
+
+// VolatileAttribute is an attribute the provider or the API changes after apply,
+// so a plan right after apply shows a diff on it even though nothing drifted.
+type VolatileAttribute struct {
+	// Type is the resource type, such as google_cloudfunctions2_function.
+	Type string
+	// Path is the attribute path with dots between segments. * matches any
+	// list index or map key. Nested attributes of the path are ignored too.
+	Path string
+	// Reason documents why the attribute is volatile.
+	Reason string
+}
+
+// VolatileAttributes are the attributes ignored by DefaultVerify in every example.
+var VolatileAttributes = []VolatileAttribute{
+	{
+		Type:   "google_cloudfunctions2_function",
+		Path:   "build_config.*.source.*.storage_source.*.generation",
+		Reason: "The Cloud Functions API rewrites the generation of the storage source to the object generation it built from.",
+	},
+}
+
+// Change is a planned change that is not explained by a volatile attribute.
+type Change struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+	// Paths are the changed attributes, empty when the resource is created or deleted.
+	Paths []string `json:"paths"`
+}
+
+func (c Change) String() string {
+	if len(c.Paths) == 0 {
+		return fmt.Sprintf("%s: %s", c.Address, strings.Join(c.Actions, ", "))
+	}
+	return fmt.Sprintf("%s: %s %s", c.Address, strings.Join(c.Actions, ", "), strings.Join(c.Paths, ", "))
+}
+
+type resourceChange struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions      []string `json:"actions"`
+		Before       any      `json:"before"`
+		After        any      `json:"after"`
+		AfterUnknown any      `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// Drift returns the changes of a plan written by terraform show -json. Volatile
+// attributes are only ignored in in-place updates: creates, deletes and replaces
+// are always changes, since a volatile attribute can't explain recreating a resource.
+func Drift(data []byte, volatile []VolatileAttribute) ([]Change, error) {
+	var p struct {
+		ResourceChanges []resourceChange `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("error parsing plan: %w", err)
+	}
+	var changes []Change
+	for _, rc := range p.ResourceChanges {
+		actions := rc.Change.Actions
+		if slices.Equal(actions, []string{"no-op"}) || slices.Equal(actions, []string{"read"}) {
+			continue
+		}
+		c := Change{Address: rc.Address, Actions: actions}
+		if rc.Change.Before == nil || rc.Change.After == nil {
+			changes = append(changes, c)
+			continue
+		}
+		var paths []string
+		diffPaths(nil, rc.Change.Before, rc.Change.After, &paths)
+		unknownPaths(nil, rc.Change.AfterUnknown, &paths)
+		update := slices.Equal(actions, []string{"update"})
+		for _, path := range paths {
+			if !slices.Contains(c.Paths, path) && !(update && isVolatile(volatile, rc.Type, path)) {
+				c.Paths = append(c.Paths, path)
+			}
+		}
+		if len(c.Paths) > 0 || !update {
+			sort.Strings(c.Paths)
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// DefaultVerify plans again after apply, like tft's DefaultVerify, and fails on
+// every change that is not explained by a volatile attribute.
+func DefaultVerify(assert *assert.Assertions, b *tft.TFBlueprintTest, volatile []VolatileAttribute) {
+	// The parsed plan is ignored: PlanAndShow already fails the test when the JSON
+	// doesn't parse, and Drift decodes the JSON itself so that it runs on plan files.
+	planJSON, _ := b.PlanAndShow()
+	changes, err := Drift([]byte(planJSON), volatile)
+	if !assert.NoError(err) {
+		return
+	}
+	for _, c := range changes {
+		assert.Fail("plan after apply should have no diff", c.String())
+	}
+}
+
+func diffPaths(path []string, before, after any, paths *[]string) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			for k := range union(b, a) {
+				diffPaths(append(slices.Clone(path), k), b[k], a[k], paths)
+			}
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			for i := 0; i < max(len(a), len(b)); i++ {
+				var bv, av any
+				if i < len(b) {
+					bv = b[i]
+				}
+				if i < len(a) {
+					av = a[i]
+				}
+				diffPaths(append(slices.Clone(path), strconv.Itoa(i)), bv, av, paths)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*paths = append(*paths, strings.Join(path, "."))
+	}
+}
+
+// unknownPaths adds the attributes marked as known after apply.
+func unknownPaths(path []string, unknown any, paths *[]string) {
+	switch u := unknown.(type) {
+	case bool:
+		if u {
+			*paths = append(*paths, strings.Join(path, "."))
+		}
+	case map[string]any:
+		for k, v := range u {
+			unknownPaths(append(slices.Clone(path), k), v, paths)
+		}
+	case []any:
+		for i, v := range u {
+			unknownPaths(append(slices.Clone(path), strconv.Itoa(i)), v, paths)
+		}
+	}
+}
+
+func union(a, b map[string]any) map[string]bool {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+func isVolatile(volatile []VolatileAttribute, typ, path string) bool {
+	segments := strings.Split(path, ".")
+	for _, v := range volatile {
+		if v.Type != typ {
+			continue
+		}
+		pattern := strings.Split(v.Path, ".")
+		if len(pattern) > len(segments) {
+			continue
+		}
+		match := true
+		for i, p := range pattern {
+			if p != "*" && p != segments[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrift(t *testing.T) {
+	data, err := os.ReadFile("testdata/drift.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Drift(data, VolatileAttributes)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{"google_cloudfunctions2_function.drifted", []string{"update"}, []string{"service_config.0.environment_variables.LOG_LEVEL", "service_config.0.max_instance_count"}},
+		{"google_storage_bucket.bucket", []string{"update"}, []string{"labels"}},
+		{"google_storage_bucket_object.function-source", []string{"delete", "create"}, []string{"md5hash", "media_link"}},
+		// Replaced and deleted resources are reported even when only a volatile attribute differs.
+		{"google_cloudfunctions2_function.replaced", []string{"delete", "create"}, []string{"build_config.0.source.0.storage_source.0.generation"}},
+		{"google_storage_bucket.removed", []string{"delete"}, nil},
+		{"google_pubsub_subscription.push", []string{"create"}, nil},
+	}, changes)
+	assert.Equal(t, "google_storage_bucket.bucket: update labels", changes[1].String())
+	assert.Equal(t, "google_pubsub_subscription.push: create", changes[5].String())
+	assert.Equal(t, "google_storage_bucket.removed: delete", changes[4].String())
+
+	// Without volatile attributes the rewritten generation is drift.
+	changes, err = Drift(data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Change{
+		Address: "module.cloud_functions2.google_cloudfunctions2_function.function",
+		Actions: []string{"update"},
+		Paths:   []string{"build_config.0.source.0.storage_source.0.generation"},
+	}, changes[0])
+
+	_, err = Drift([]byte("{"), nil)
+	assert.Error(t, err)
+}
+
+func TestIsVolatile(t *testing.T) {
+	volatile := []VolatileAttribute{{Type: "google_storage_bucket", Path: "labels.*", Reason: "test"}}
+	for _, tt := range []struct {
+		typ, path string
+		want      bool
+	}{
+		{"google_storage_bucket", "labels.env", true},
+		{"google_storage_bucket", "labels.env.nested", true},
+		{"google_storage_bucket", "labels", false},
+		{"google_storage_bucket", "location", false},
+		{"google_pubsub_topic", "labels.env", false},
+	} {
+		assert.Equal(t, tt.want, isVolatile(volatile, tt.typ, tt.path), "%s %s", tt.typ, tt.path)
+	}
+}
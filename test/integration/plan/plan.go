@@ -0,0 +1,112 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plan reads the JSON representation of a Terraform plan
+// (terraform show -json) so tests can check the planned resources without deploying them.
+package plan
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/tidwall/gjson"
+)
+
+// Plan holds the planned resources of every module of a Terraform plan.
+type Plan struct {
+	TerraformVersion string
+	resources        []Resource
+}
+
+// Resource is a managed resource of planned_values.
+// Values omits the attributes only known after apply, which Unknown marks true.
+type Resource struct {
+	Address string
+	Type    string
+	Name    string
+	Values  gjson.Result
+	Unknown gjson.Result
+}
+
+// Load reads a plan file written by terraform show -json.
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes the output of terraform show -json.
+func Parse(data []byte) (*Plan, error) {
+	if !gjson.ValidBytes(data) {
+		return nil, fmt.Errorf("plan is not valid JSON")
+	}
+	r := gjson.ParseBytes(data)
+	root := r.Get("planned_values.root_module")
+	if !root.Exists() {
+		return nil, fmt.Errorf("plan has no planned_values, use terraform show -json on a saved plan")
+	}
+	unknown := make(map[string]gjson.Result)
+	for _, c := range r.Get("resource_changes").Array() {
+		unknown[c.Get("address").String()] = c.Get("change.after_unknown")
+	}
+	p := &Plan{TerraformVersion: r.Get("terraform_version").String()}
+	p.addModule(root, unknown)
+	return p, nil
+}
+
+func (p *Plan) addModule(m gjson.Result, unknown map[string]gjson.Result) {
+	for _, r := range m.Get("resources").Array() {
+		if r.Get("mode").String() != "managed" {
+			continue
+		}
+		p.resources = append(p.resources, Resource{
+			Address: r.Get("address").String(),
+			Type:    r.Get("type").String(),
+			Name:    r.Get("name").String(),
+			Values:  r.Get("values"),
+			Unknown: unknown[r.Get("address").String()],
+		})
+	}
+	for _, c := range m.Get("child_modules").Array() {
+		p.addModule(c, unknown)
+	}
+}
+
+// Resources returns the planned resources of the given types, or every resource when no type is given.
+func (p *Plan) Resources(types ...string) []Resource {
+	var res []Resource
+	for _, r := range p.resources {
+		if len(types) == 0 || slices.Contains(types, r.Type) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Resource returns the resource at address.
+func (p *Plan) Resource(address string) (Resource, bool) {
+	for _, r := range p.resources {
+		if r.Address == address {
+			return r, true
+		}
+	}
+	return Resource{}, false
+}
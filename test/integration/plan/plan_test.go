@@ -0,0 +1,80 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	p, err := Load("testdata/insecure.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1.5.7", p.TerraformVersion)
+	assert.Len(t, p.Resources(), 7)
+	assert.Len(t, p.Resources("google_kms_crypto_key", "google_compute_firewall"), 3)
+	_, ok := p.Resource("google_compute_firewall.open")
+	assert.True(t, ok)
+
+	assert.Equal(t, []Finding{
+		{CheckFunctionSource, "google_cloudfunctions2_function.both_sources", "build source has 1 storage_source and 1 repo_source blocks, expected exactly one"},
+		{CheckEgressWithoutConnector, "google_cloudfunctions2_function.both_sources", "vpc_connector_egress_settings is ALL_TRAFFIC without a vpc_connector"},
+		{CheckFunctionSource, "google_cloudfunctions2_function.unknown_connector", "build source has 0 storage_source and 0 repo_source blocks, expected exactly one"},
+		{CheckFirewallLogging, "google_compute_firewall.open", "logging is disabled"},
+		{CheckFirewallOpenIngress, "google_compute_firewall.open", "allows ingress from 0.0.0.0/0"},
+		{CheckKMSRotation, "google_kms_crypto_key.yearly", "rotation_period 31536000s is longer than 7776000s"},
+		{CheckKMSRotation, "google_kms_crypto_key.never", "rotation_period is not set"},
+		{CheckIAMPublicMember, "google_cloudfunctions2_function_iam_member.public", "grants roles/cloudfunctions.invoker to allUsers"},
+		{CheckIAMPrimitiveRole, "google_project_iam_binding.owners", "grants primitive role roles/owner"},
+		{CheckIAMPublicMember, "google_project_iam_binding.owners", "grants roles/owner to allAuthenticatedUsers"},
+	}, Verify(p))
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name, data, wantErr string
+	}{
+		{"invalid", "{", "plan is not valid JSON"},
+		{"no planned values", `{"format_version": "1.0", "prior_state": {}}`, "plan has no planned_values, use terraform show -json on a saved plan"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	p, err := Parse([]byte(`{
+		"planned_values": {"root_module": {"resources": [
+			{"address": "google_kms_crypto_key.key", "mode": "managed", "type": "google_kms_crypto_key", "name": "key", "values": {"name": "key"}}
+		]}},
+		"resource_changes": [
+			{"address": "google_kms_crypto_key.key", "change": {"actions": ["create"], "after": {"name": "key"}, "after_unknown": {"id": true, "key_ring": true}}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := p.Resource("google_kms_crypto_key.key")
+	if assert.True(t, ok) {
+		assert.False(t, r.Values.Get("key_ring").Exists())
+		assert.True(t, r.Unknown.Get("key_ring").Bool())
+		assert.False(t, r.Unknown.Get("name").Bool())
+	}
+}
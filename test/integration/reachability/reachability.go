@@ -0,0 +1,238 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reachability simulates how the VPC firewall evaluates egress traffic
+// leaving the instances of a network, such as a Serverless VPC Access connector.
+package reachability
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+const (
+	// ImpliedAllowEgress is the rule reported when no firewall rule matches.
+	ImpliedAllowEgress = "implied-allow-egress"
+	maxPort            = 65535
+)
+
+// Instance is the source of the simulated traffic.
+type Instance struct {
+	Tags           []string
+	ServiceAccount string
+}
+
+// ConnectorInstance returns the instances of a Serverless VPC Access connector,
+// which carry the vpc-connector network tags.
+func ConnectorInstance(region, name string) Instance {
+	return Instance{Tags: []string{"vpc-connector", fmt.Sprintf("vpc-connector-%s-%s", region, name)}}
+}
+
+// Decision is the result of evaluating a connection.
+type Decision struct {
+	Allowed bool
+	// Rule is the name of the firewall rule that decided, or ImpliedAllowEgress.
+	Rule string
+}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	From int
+	To   int
+}
+
+func (p PortRange) String() string {
+	if p.From == p.To {
+		return strconv.Itoa(p.From)
+	}
+	return fmt.Sprintf("%d-%d", p.From, p.To)
+}
+
+// Simulator evaluates egress connections against a set of firewall rules.
+type Simulator struct {
+	rules []rule
+}
+
+type rule struct {
+	testutils.FirewallRule
+	destinations []netip.Prefix
+	entries      []entry
+	allow        bool
+}
+
+type entry struct {
+	protocol string
+	ports    []PortRange
+}
+
+// New returns a Simulator for the enabled egress rules of a network.
+func New(rules []testutils.FirewallRule) (*Simulator, error) {
+	s := &Simulator{}
+	for _, r := range rules {
+		if r.Disabled || r.Direction != "EGRESS" {
+			continue
+		}
+		sr := rule{FirewallRule: r, allow: len(r.Allowed) > 0}
+		ranges := r.DestinationRanges
+		if len(ranges) == 0 {
+			ranges = []string{"0.0.0.0/0"}
+		}
+		for _, dr := range ranges {
+			p, err := parsePrefix(dr)
+			if err != nil {
+				return nil, fmt.Errorf("firewall rule %s: %w", r.Name, err)
+			}
+			sr.destinations = append(sr.destinations, p)
+		}
+		protocols := r.Denied
+		if sr.allow {
+			protocols = r.Allowed
+		}
+		for _, p := range protocols {
+			e := entry{protocol: p.Protocol}
+			for _, port := range p.Ports {
+				pr, err := parsePortRange(port)
+				if err != nil {
+					return nil, fmt.Errorf("firewall rule %s: %w", r.Name, err)
+				}
+				e.ports = append(e.ports, pr)
+			}
+			sr.entries = append(sr.entries, e)
+		}
+		s.rules = append(s.rules, sr)
+	}
+	// Lower priority values are evaluated first and a deny wins over an allow of the same priority.
+	slices.SortStableFunc(s.rules, func(a, b rule) int {
+		if a.Priority != b.Priority {
+			return a.Priority - b.Priority
+		}
+		if a.allow == b.allow {
+			return 0
+		}
+		if a.allow {
+			return 1
+		}
+		return -1
+	})
+	return s, nil
+}
+
+// Egress evaluates a connection from src to ip on protocol and port.
+func (s *Simulator) Egress(src Instance, ip netip.Addr, protocol string, port int) Decision {
+	for _, r := range s.rules {
+		if r.appliesTo(src) && r.matches(ip, protocol, port) {
+			return Decision{Allowed: r.allow, Rule: r.Name}
+		}
+	}
+	return Decision{Allowed: true, Rule: ImpliedAllowEgress}
+}
+
+// AllowedPorts returns every port src can reach on ip over protocol, merged into ranges.
+func (s *Simulator) AllowedPorts(src Instance, ip netip.Addr, protocol string) []PortRange {
+	// Evaluation only changes at the boundaries of the port ranges in the rules.
+	bounds := []int{0, maxPort + 1}
+	for _, r := range s.rules {
+		for _, e := range r.entries {
+			for _, p := range e.ports {
+				bounds = append(bounds, p.From, p.To+1)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var allowed []PortRange
+	for i := 0; i < len(bounds)-1; i++ {
+		from, to := bounds[i], bounds[i+1]-1
+		if !s.Egress(src, ip, protocol, from).Allowed {
+			continue
+		}
+		if n := len(allowed); n > 0 && allowed[n-1].To == from-1 {
+			allowed[n-1].To = to
+			continue
+		}
+		allowed = append(allowed, PortRange{From: from, To: to})
+	}
+	return allowed
+}
+
+func (r rule) appliesTo(src Instance) bool {
+	if len(r.TargetTags) == 0 && len(r.TargetServiceAccounts) == 0 {
+		return true
+	}
+	for _, tag := range r.TargetTags {
+		if slices.Contains(src.Tags, tag) {
+			return true
+		}
+	}
+	return src.ServiceAccount != "" && slices.Contains(r.TargetServiceAccounts, src.ServiceAccount)
+}
+
+func (r rule) matches(ip netip.Addr, protocol string, port int) bool {
+	if !slices.ContainsFunc(r.destinations, func(p netip.Prefix) bool { return p.Contains(ip) }) {
+		return false
+	}
+	for _, e := range r.entries {
+		if e.protocol != "all" && e.protocol != protocol {
+			continue
+		}
+		if len(e.ports) == 0 {
+			return true
+		}
+		for _, p := range e.ports {
+			if port >= p.From && port <= p.To {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parsePrefix parses a CIDR range or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid destination range %q: %w", s, err)
+		}
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid destination range %q: %w", s, err)
+	}
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// parsePortRange parses a port such as "443" or a range such as "8000-8080".
+func parsePortRange(s string) (PortRange, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	f, err := strconv.Atoi(from)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port %q", s)
+	}
+	t, err := strconv.Atoi(to)
+	if err != nil || f > t || f < 0 || t > maxPort {
+		return PortRange{}, fmt.Errorf("invalid port %q", s)
+	}
+	return PortRange{From: f, To: t}, nil
+}
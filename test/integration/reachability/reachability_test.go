@@ -0,0 +1,126 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reachability
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+// restrictedRules are the egress rules of the restricted network in the cf-to-sql blueprint.
+var restrictedRules = []testutils.FirewallRule{
+	{
+		Name:              "fw-e-shared-restricted-65535-e-d-all-all-all",
+		Direction:         "EGRESS",
+		Priority:          65535,
+		DestinationRanges: []string{"0.0.0.0/0"},
+		Denied:            []testutils.Protocols{{Protocol: "all"}},
+	},
+	{
+		Name:              "fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443",
+		Direction:         "EGRESS",
+		Priority:          65534,
+		DestinationRanges: []string{"10.3.0.5"},
+		TargetTags:        []string{"allow-google-apis"},
+		Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"443"}}},
+	},
+	{
+		Name:              "fw-allow-tcp-443-egress-to-secure-web-proxy",
+		Direction:         "EGRESS",
+		Priority:          100,
+		DestinationRanges: []string{"10.129.0.0/23", "10.0.0.0/28"},
+		Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"443"}}},
+	},
+	{
+		Name:              "fw-allow-tcp-3307-egress-to-sql-private-ip",
+		Direction:         "EGRESS",
+		Priority:          100,
+		DestinationRanges: []string{"10.9.0.3"},
+		Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"3307"}}},
+	},
+}
+
+func TestEgress(t *testing.T) {
+	sim, err := New(restrictedRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := ConnectorInstance("us-central1", "con-secure-cloud-function")
+	tagged := Instance{Tags: []string{"allow-google-apis"}}
+
+	tests := []struct {
+		name     string
+		src      Instance
+		ip       string
+		protocol string
+		port     int
+		want     Decision
+	}{
+		{"sql", connector, "10.9.0.3", "tcp", 3307, Decision{Allowed: true, Rule: "fw-allow-tcp-3307-egress-to-sql-private-ip"}},
+		{"sql other port", connector, "10.9.0.3", "tcp", 3306, Decision{Rule: "fw-e-shared-restricted-65535-e-d-all-all-all"}},
+		{"swp", connector, "10.129.1.20", "tcp", 443, Decision{Allowed: true, Rule: "fw-allow-tcp-443-egress-to-secure-web-proxy"}},
+		{"swp udp", connector, "10.129.1.20", "udp", 443, Decision{Rule: "fw-e-shared-restricted-65535-e-d-all-all-all"}},
+		{"restricted apis untagged", connector, "10.3.0.5", "tcp", 443, Decision{Rule: "fw-e-shared-restricted-65535-e-d-all-all-all"}},
+		{"restricted apis tagged", tagged, "10.3.0.5", "tcp", 443, Decision{Allowed: true, Rule: "fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443"}},
+		{"internet", connector, "8.8.8.8", "tcp", 443, Decision{Rule: "fw-e-shared-restricted-65535-e-d-all-all-all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sim.Egress(tt.src, netip.MustParseAddr(tt.ip), tt.protocol, tt.port))
+		})
+	}
+}
+
+func TestEgressPriority(t *testing.T) {
+	rules := []testutils.FirewallRule{
+		{Name: "allow-1000", Direction: "EGRESS", Priority: 1000, Allowed: []testutils.Protocols{{Protocol: "tcp"}}},
+		{Name: "deny-1000", Direction: "EGRESS", Priority: 1000, Denied: []testutils.Protocols{{Protocol: "tcp", Ports: []string{"22"}}}},
+		{Name: "allow-10-disabled", Direction: "EGRESS", Priority: 10, Disabled: true, Allowed: []testutils.Protocols{{Protocol: "all"}}},
+		{Name: "deny-ingress", Direction: "INGRESS", Priority: 0, Denied: []testutils.Protocols{{Protocol: "all"}}},
+	}
+	sim, err := New(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := netip.MustParseAddr("203.0.113.7")
+	assert.Equal(t, Decision{Rule: "deny-1000"}, sim.Egress(Instance{}, ip, "tcp", 22))
+	assert.Equal(t, Decision{Allowed: true, Rule: "allow-1000"}, sim.Egress(Instance{}, ip, "tcp", 80))
+	assert.Equal(t, Decision{Allowed: true, Rule: ImpliedAllowEgress}, sim.Egress(Instance{}, ip, "udp", 53))
+	assert.Equal(t, []PortRange{{From: 0, To: 21}, {From: 23, To: 65535}}, sim.AllowedPorts(Instance{}, ip, "tcp"))
+}
+
+func TestAllowedPorts(t *testing.T) {
+	sim, err := New(restrictedRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := ConnectorInstance("us-central1", "con-secure-cloud-function")
+	assert.Equal(t, []PortRange{{From: 3307, To: 3307}}, sim.AllowedPorts(connector, netip.MustParseAddr("10.9.0.3"), "tcp"))
+	assert.Empty(t, sim.AllowedPorts(connector, netip.MustParseAddr("10.9.0.3"), "udp"))
+	assert.Empty(t, sim.AllowedPorts(connector, netip.MustParseAddr("8.8.8.8"), "tcp"))
+}
+
+func TestNewInvalidRule(t *testing.T) {
+	for _, r := range []testutils.FirewallRule{
+		{Name: "bad-range", Direction: "EGRESS", DestinationRanges: []string{"10.0.0.0/33"}, Allowed: []testutils.Protocols{{Protocol: "tcp"}}},
+		{Name: "bad-port", Direction: "EGRESS", Allowed: []testutils.Protocols{{Protocol: "tcp", Ports: []string{"9000-8000"}}}},
+	} {
+		_, err := New([]testutils.FirewallRule{r})
+		assert.ErrorContains(t, err, r.Name)
+	}
+}
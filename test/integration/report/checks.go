@@ -0,0 +1,103 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"slices"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+)
+
+// Control is the security control a check provides evidence for.
+type Control string
+
+// Severity is the impact of a failed check.
+type Severity string
+
+const (
+	ControlDeployment Control = "deployment"
+	ControlIngress    Control = "ingress"
+	ControlEgress     Control = "egress"
+	ControlCMEK       Control = "cmek"
+	ControlIdentity   Control = "identity"
+	ControlPerimeter  Control = "perimeter"
+	ControlOrgPolicy  Control = "org-policy"
+
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+// Check is a named verification. IDs are stable: never reuse or renumber them,
+// add a new check instead.
+type Check struct {
+	ID       string
+	Title    string
+	Control  Control
+	Severity Severity
+}
+
+var (
+	FunctionActive        = Check{"DEP-001", "Cloud Function is active", ControlDeployment, SeverityHigh}
+	FunctionSource        = Check{"DEP-002", "Cloud Function is built from the expected source", ControlDeployment, SeverityMedium}
+	FunctionTrigger       = Check{"DEP-003", "Cloud Function has the expected event trigger", ControlDeployment, SeverityMedium}
+	FunctionConfiguration = Check{"DEP-004", "Cloud Function has the expected environment and secrets", ControlDeployment, SeverityLow}
+	Resources             = Check{"DEP-005", "Supporting resources are deployed as configured", ControlDeployment, SeverityLow}
+
+	FunctionIngress = Check{"ING-001", "Cloud Function only accepts internal ingress", ControlIngress, SeverityCritical}
+	PrivateDatabase = Check{"ING-002", "Cloud SQL only has private IP addresses", ControlIngress, SeverityCritical}
+
+	FunctionEgress     = Check{"EGR-001", "Cloud Function sends all egress through the VPC connector", ControlEgress, SeverityCritical}
+	FirewallRules      = Check{"EGR-002", "Firewall rules match the expected rules", ControlEgress, SeverityHigh}
+	EgressReachability = Check{"EGR-003", "VPC connector only reaches the allowed destinations", ControlEgress, SeverityCritical}
+	PrivateWorkerPool  = Check{"EGR-004", "Builds run in a private worker pool without public egress", ControlEgress, SeverityHigh}
+	SecureWebProxy     = Check{"EGR-005", "Secure Web Proxy only allows the URL lists", ControlEgress, SeverityHigh}
+	Network            = Check{"EGR-006", "Network and subnets have the expected routing mode and ranges", ControlEgress, SeverityMedium}
+	VPCConnector       = Check{"EGR-007", "VPC connector has the expected subnet, machine type and scaling", ControlEgress, SeverityMedium}
+	NetworkPeering     = Check{"EGR-008", "Network has a single private service access peering", ControlEgress, SeverityMedium}
+	AddressPlan        = Check{"EGR-009", "Subnet, proxy, restricted API and private service access ranges don't overlap", ControlEgress, SeverityMedium}
+
+	FunctionCMEK = Check{"CMEK-001", "Cloud Function sources, images and events are encrypted with a customer managed key", ControlCMEK, SeverityCritical}
+	DataCMEK     = Check{"CMEK-002", "Data stores are encrypted with a customer managed key", ControlCMEK, SeverityCritical}
+	CMEKCoverage = Check{"CMEK-003", "Customer managed keys match the location, rotation, protection level and grants of the resources they encrypt", ControlCMEK, SeverityHigh}
+
+	FunctionIdentity = Check{"IAM-001", "Cloud Function runs as a dedicated service account", ControlIdentity, SeverityHigh}
+
+	Perimeter = Check{"VPCSC-001", "VPC Service Controls perimeter restricts the catalog services", ControlPerimeter, SeverityCritical}
+
+	OrgPolicies = Check{"ORG-001", "Organization policies enforce the serverless constraints", ControlOrgPolicy, SeverityHigh}
+)
+
+// AssertNoViolations fails for each violation of one of rules.
+func AssertNoViolations(assert *assert.Assertions, violations []audit.Violation, rules ...string) {
+	for _, v := range violations {
+		if slices.Contains(rules, v.Rule) {
+			assert.Fail("Cloud Function does not comply with the secure baseline.", v.String())
+		}
+	}
+}
+
+// AssertCMEK fails unless b holds the repository of the function and its expected key, so that
+// the CMEK evidence is only recorded once the key has been compared, and then for each violation
+// of the repository rule.
+func AssertCMEK(assert *assert.Assertions, b audit.Baseline, violations []audit.Violation) {
+	if !assert.NotNil(b.Repository, "Baseline should describe the Artifact Registry repository of the function.") ||
+		!assert.NotEmpty(b.KMSKey, "Baseline should have the expected KMS key of the repository.") {
+		return
+	}
+	AssertNoViolations(assert, violations, audit.RuleCMEKRepository)
+}
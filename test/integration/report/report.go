@@ -0,0 +1,246 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report records the outcome of named verification checks and writes
+// them as JUnit XML and JSON, so each run leaves evidence of which controls were verified.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// DirEnv is the environment variable with the directory the reports are written to.
+// Nothing is written when it is not set. testutils writes its transient error
+// summaries to the same directory.
+const DirEnv = "TEST_REPORT_DIR"
+
+// Result is the outcome of a check on one resource.
+type Result struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Control  Control  `json:"control"`
+	Severity Severity `json:"severity"`
+	Resource string   `json:"resource"`
+	Passed   bool     `json:"passed"`
+	// Failures are the messages of the failed assertions.
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Summary counts the passed and failed results.
+type Summary struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+// Assertions are the assertions passed to Check. The alias lets test
+// functions with an assert parameter name the type.
+type Assertions = assert.Assertions
+
+// Report holds the results of a test.
+type Report struct {
+	Suite   string    `json:"suite"`
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"results"`
+	t       testing.TB
+}
+
+// New returns the report of t. The report is written to the directory in
+// DirEnv when t finishes.
+func New(t testing.TB) *Report {
+	r := &Report{Suite: t.Name(), Time: time.Now().UTC(), t: t}
+	if dir := os.Getenv(DirEnv); dir != "" {
+		t.Cleanup(func() {
+			if err := r.Write(dir); err != nil {
+				t.Errorf("error writing test report: %v", err)
+			}
+		})
+	}
+	return r
+}
+
+// Check runs the assertions of c on resource and records the result.
+// A failed assertion fails the test as usual and is recorded in the report.
+func (r *Report) Check(c Check, resource string, assertions func(assert *Assertions)) bool {
+	rec := &recorder{t: r.t}
+	assertions(assert.New(rec))
+	r.Results = append(r.Results, Result{
+		ID:       c.ID,
+		Title:    c.Title,
+		Control:  c.Control,
+		Severity: c.Severity,
+		Resource: resource,
+		Passed:   len(rec.failures) == 0,
+		Failures: rec.failures,
+	})
+	return len(rec.failures) == 0
+}
+
+// recorder forwards assertion failures to the test and keeps their messages.
+type recorder struct {
+	t        testing.TB
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.t.Helper()
+	r.failures = append(r.failures, failureMessage(fmt.Sprintf(format, args...)))
+	r.t.Errorf(format, args...)
+}
+
+func (r *recorder) Helper() {
+	r.t.Helper()
+}
+
+// failureMessage keeps the Error and Messages fields of a testify failure, dropping the trace.
+func failureMessage(s string) string {
+	var lines []string
+	keep := false
+	for _, l := range strings.Split(s, "\n") {
+		field := strings.TrimSpace(l)
+		switch {
+		case strings.HasPrefix(field, "Error Trace:"), strings.HasPrefix(field, "Test:"):
+			keep = false
+			continue
+		case strings.HasPrefix(field, "Error:"), strings.HasPrefix(field, "Messages:"):
+			keep = true
+		}
+		if keep && field != "" {
+			lines = append(lines, field)
+		}
+	}
+	if len(lines) == 0 {
+		return strings.TrimSpace(s)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Summary counts the results of each control.
+func (r *Report) Summary() map[Control]Summary {
+	s := map[Control]Summary{}
+	for _, res := range r.Results {
+		c := s[res.Control]
+		if res.Passed {
+			c.Passed++
+		} else {
+			c.Failed++
+		}
+		s[res.Control] = c
+	}
+	return s
+}
+
+// Write writes <suite>.json and <suite>.xml to dir.
+func (r *Report) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(r.Suite)
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		return err
+	}
+	data, err = r.JUnit()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".xml"), data, 0644)
+}
+
+// JSON returns the report with a summary per control.
+func (r *Report) JSON() ([]byte, error) {
+	results := r.Results
+	if results == nil {
+		results = []Result{}
+	}
+	return json.MarshalIndent(struct {
+		Suite   string              `json:"suite"`
+		Time    time.Time           `json:"time"`
+		Summary map[Control]Summary `json:"summary"`
+		Results []Result            `json:"results"`
+	}{r.Suite, r.Time, r.Summary(), results}, "", "  ")
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitFailure   `xml:"failure"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns the report as a JUnit XML document with one test case per result.
+func (r *Report) JUnit() ([]byte, error) {
+	s := junitSuite{Name: r.Suite, Tests: len(r.Results), Timestamp: r.Time.Format(time.RFC3339)}
+	for _, res := range r.Results {
+		c := junitCase{
+			Name:      fmt.Sprintf("%s %s", res.ID, res.Title),
+			Classname: fmt.Sprintf("%s.%s", r.Suite, res.Control),
+			Properties: []junitProperty{
+				{"id", res.ID},
+				{"control", string(res.Control)},
+				{"severity", string(res.Severity)},
+				{"resource", res.Resource},
+			},
+		}
+		if !res.Passed {
+			s.Failures++
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s failed on %s", res.ID, res.Resource),
+				Type:    string(res.Severity),
+				Text:    strings.Join(res.Failures, "\n\n"),
+			}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{s}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+)
+
+// fakeT records the failures of the checks instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Name() string { return "TestFake/verify" }
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func sampleReport() (*Report, *fakeT) {
+	ft := &fakeT{}
+	r := New(ft)
+	r.Time = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r.Check(FunctionIngress, "functions/f", func(assert *Assertions) {
+		assert.Equal("ALLOW_INTERNAL_AND_GCLB", "ALLOW_INTERNAL_AND_GCLB")
+	})
+	r.Check(FunctionCMEK, "functions/f", func(assert *Assertions) {
+		assert.Equal("key-a", "key-b", "Should have KMS Key: key-a")
+	})
+	return r, ft
+}
+
+func TestCheck(t *testing.T) {
+	r, ft := sampleReport()
+	assert := assert.New(t)
+
+	assert.Len(ft.errors, 1, "a failed check should fail the test")
+	if assert.Len(r.Results, 2) {
+		assert.True(r.Results[0].Passed)
+		assert.Empty(r.Results[0].Failures)
+		assert.Equal("ING-001", r.Results[0].ID)
+		assert.False(r.Results[1].Passed)
+		if assert.Len(r.Results[1].Failures, 1) {
+			assert.Contains(r.Results[1].Failures[0], "Should have KMS Key: key-a")
+			assert.NotContains(r.Results[1].Failures[0], "Error Trace:")
+		}
+	}
+	assert.Equal(map[Control]Summary{
+		ControlIngress: {Passed: 1},
+		ControlCMEK:    {Failed: 1},
+	}, r.Summary())
+}
+
+func TestFailureMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "testify failure",
+			in:   "\n\tError Trace:\t/src/a_test.go:10\n\tError:      \tNot equal: \n\t            \texpected: 1\n\tTest:       \tTestA\n\tMessages:   \tshould be one\n",
+			want: "Error:      \tNot equal:\nexpected: 1\nMessages:   \tshould be one",
+		},
+		{
+			name: "plain message",
+			in:   "  something failed  ",
+			want: "something failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, failureMessage(tt.in))
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r, _ := sampleReport()
+	data, err := r.JSON()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var got struct {
+		Suite   string              `json:"suite"`
+		Summary map[Control]Summary `json:"summary"`
+		Results []Result            `json:"results"`
+	}
+	if assert.NoError(t, json.Unmarshal(data, &got)) {
+		assert.Equal(t, "TestFake/verify", got.Suite)
+		assert.Equal(t, Summary{Failed: 1}, got.Summary[ControlCMEK])
+		assert.Equal(t, r.Results, got.Results)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	r, _ := sampleReport()
+	data, err := r.JUnit()
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc := string(data)
+	assert.Contains(t, doc, `<testsuite name="TestFake/verify" tests="2" failures="1" timestamp="2026-01-02T03:04:05Z">`)
+	assert.Contains(t, doc, `<testcase name="ING-001 Cloud Function only accepts internal ingress" classname="TestFake/verify.ingress">`)
+	assert.Contains(t, doc, `<property name="severity" value="critical"></property>`)
+	assert.Contains(t, doc, `<failure message="CMEK-001 failed on functions/f" type="critical">`)
+	assert.Equal(t, 1, strings.Count(doc, "<failure"))
+}
+
+func TestDirEnv(t *testing.T) {
+	assert.Equal(t, testutils.ReportDirEnv, DirEnv, "reports and transient error summaries should be written to the same directory")
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(DirEnv, dir)
+	r, ft := sampleReport()
+	if assert.Len(t, ft.cleanups, 1, "New should write the report when the test finishes") {
+		ft.cleanups[0]()
+	}
+	assert.Empty(t, ft.errors[1:])
+	for _, name := range []string{"TestFake_verify.json", "TestFake_verify.xml"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err, name)
+	}
+	assert.NotEmpty(t, r.Results)
+}
+
+func TestAssertNoViolations(t *testing.T) {
+	violations := []audit.Violation{
+		{Rule: audit.RuleInternalIngress, Message: "ingress setting is \"ALLOW_ALL\""},
+		{Rule: audit.RuleDedicatedSA, Message: "function runs as the default service account"},
+	}
+	tests := []struct {
+		name  string
+		rules []string
+		fails int
+	}{
+		{name: "matching rule", rules: []string{audit.RuleInternalIngress}, fails: 1},
+		{name: "several rules", rules: []string{audit.RuleInternalIngress, audit.RuleDedicatedSA}, fails: 2},
+		{name: "other rules", rules: []string{audit.RuleVPCConnector}, fails: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			AssertNoViolations(assert.New(ft), violations, tt.rules...)
+			assert.Len(t, ft.errors, tt.fails)
+		})
+	}
+}
+
+func TestAssertCMEK(t *testing.T) {
+	key := "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	repo := &audit.ArtifactRepository{Name: "projects/p/locations/l/repositories/r", Format: "DOCKER", KMSKeyName: key}
+	violations := []audit.Violation{{Rule: audit.RuleDedicatedSA, Message: "function runs as the default service account"}}
+	tests := []struct {
+		name       string
+		baseline   audit.Baseline
+		violations []audit.Violation
+		fails      int
+	}{
+		{name: "compared key", baseline: audit.Baseline{Repository: repo, KMSKey: key}, violations: violations},
+		{name: "key mismatch", baseline: audit.Baseline{Repository: repo, KMSKey: key},
+			violations: append(violations, audit.Violation{Rule: audit.RuleCMEKRepository, Message: "repository r is encrypted with k2, expected k"}), fails: 1},
+		{name: "no repository", baseline: audit.Baseline{KMSKey: key}, violations: violations, fails: 1},
+		{name: "no key", baseline: audit.Baseline{Repository: repo}, violations: violations, fails: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			AssertCMEK(assert.New(ft), tt.baseline, tt.violations)
+			assert.Len(t, ft.errors, tt.fails)
+		})
+	}
+}
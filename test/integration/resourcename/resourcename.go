@@ -0,0 +1,341 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resourcename parses and formats the full resource names of the
+// resources created by the blueprints, such as
+// projects/<project>/locations/<location>/keyRings/<key ring>/cryptoKeys/<key>.
+package resourcename
+
+import (
+	"fmt"
+	"strings"
+)
+
+// template is the shape of a resource name: literal collection segments
+// alternating with {variable} segments.
+type template struct {
+	kind     string
+	pattern  string
+	segments []string
+}
+
+func newTemplate(kind, pattern string) template {
+	return template{kind: kind, pattern: pattern, segments: strings.Split(pattern, "/")}
+}
+
+func isVariable(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// parse returns the variable segments of name in order.
+func (t template) parse(name string) ([]string, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != len(t.segments) {
+		return nil, t.errorf(name)
+	}
+	var values []string
+	for i, s := range t.segments {
+		switch {
+		case !isVariable(s) && parts[i] != s:
+			return nil, t.errorf(name)
+		case isVariable(s) && parts[i] == "":
+			return nil, fmt.Errorf("%s name %q has an empty %s", t.kind, name, strings.Trim(s, "{}"))
+		case isVariable(s):
+			values = append(values, parts[i])
+		}
+	}
+	return values, nil
+}
+
+// format replaces the variable segments with values in order.
+func (t template) format(values ...string) string {
+	parts := make([]string, len(t.segments))
+	n := 0
+	for i, s := range t.segments {
+		if isVariable(s) && n < len(values) {
+			parts[i] = values[n]
+			n++
+			continue
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, "/")
+}
+
+func (t template) errorf(name string) error {
+	return fmt.Errorf("%q is not a %s name, expected %s", name, t.kind, t.pattern)
+}
+
+var (
+	cryptoKeyTemplate             = newTemplate("crypto key", "projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}")
+	cryptoKeyVersionTemplate      = newTemplate("crypto key version", "projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}/cryptoKeyVersions/{version}")
+	secretTemplate                = newTemplate("secret", "projects/{project}/secrets/{secret}")
+	secretVersionTemplate         = newTemplate("secret version", "projects/{project}/secrets/{secret}/versions/{version}")
+	topicTemplate                 = newTemplate("topic", "projects/{project}/topics/{topic}")
+	connectorTemplate             = newTemplate("connector", "projects/{project}/locations/{location}/connectors/{connector}")
+	gatewayTemplate               = newTemplate("gateway", "projects/{project}/locations/{location}/gateways/{gateway}")
+	gatewaySecurityPolicyTemplate = newTemplate("gateway security policy", "projects/{project}/locations/{location}/gatewaySecurityPolicies/{policy}")
+	urlListTemplate               = newTemplate("url list", "projects/{project}/locations/{location}/urlLists/{urlList}")
+	servicePerimeterTemplate      = newTemplate("service perimeter", "accessPolicies/{policy}/servicePerimeters/{perimeter}")
+	accessLevelTemplate           = newTemplate("access level", "accessPolicies/{policy}/accessLevels/{level}")
+	workerPoolTemplate            = newTemplate("worker pool", "projects/{project}/locations/{location}/workerPools/{workerPool}")
+)
+
+// CryptoKey is a Cloud KMS key.
+type CryptoKey struct {
+	Project  string
+	Location string
+	KeyRing  string
+	Name     string
+}
+
+// ParseCryptoKey parses projects/<project>/locations/<location>/keyRings/<key ring>/cryptoKeys/<key>.
+func ParseCryptoKey(name string) (CryptoKey, error) {
+	v, err := cryptoKeyTemplate.parse(name)
+	if err != nil {
+		return CryptoKey{}, err
+	}
+	return CryptoKey{Project: v[0], Location: v[1], KeyRing: v[2], Name: v[3]}, nil
+}
+
+func (k CryptoKey) String() string {
+	return cryptoKeyTemplate.format(k.Project, k.Location, k.KeyRing, k.Name)
+}
+
+// Version returns the version of the key.
+func (k CryptoKey) Version(version string) CryptoKeyVersion {
+	return CryptoKeyVersion{CryptoKey: k, Version: version}
+}
+
+// CryptoKeyVersion is a version of a Cloud KMS key.
+type CryptoKeyVersion struct {
+	CryptoKey
+	Version string
+}
+
+// ParseCryptoKeyVersion parses <crypto key>/cryptoKeyVersions/<version>. The version must be a number.
+func ParseCryptoKeyVersion(name string) (CryptoKeyVersion, error) {
+	v, err := cryptoKeyVersionTemplate.parse(name)
+	if err != nil {
+		return CryptoKeyVersion{}, err
+	}
+	if strings.Trim(v[4], "0123456789") != "" {
+		return CryptoKeyVersion{}, fmt.Errorf("crypto key version name %q has a version that is not a number", name)
+	}
+	return CryptoKeyVersion{CryptoKey: CryptoKey{Project: v[0], Location: v[1], KeyRing: v[2], Name: v[3]}, Version: v[4]}, nil
+}
+
+func (k CryptoKeyVersion) String() string {
+	return cryptoKeyVersionTemplate.format(k.Project, k.Location, k.KeyRing, k.Name, k.Version)
+}
+
+// Secret is a Secret Manager secret.
+type Secret struct {
+	Project string
+	Name    string
+}
+
+// ParseSecret parses projects/<project>/secrets/<secret>.
+func ParseSecret(name string) (Secret, error) {
+	v, err := secretTemplate.parse(name)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{Project: v[0], Name: v[1]}, nil
+}
+
+func (s Secret) String() string {
+	return secretTemplate.format(s.Project, s.Name)
+}
+
+// Version returns the version of the secret.
+func (s Secret) Version(version string) SecretVersion {
+	return SecretVersion{Secret: s, Version: version}
+}
+
+// SecretVersion is a version of a Secret Manager secret.
+type SecretVersion struct {
+	Secret
+	Version string
+}
+
+// ParseSecretVersion parses projects/<project>/secrets/<secret>/versions/<version>.
+func ParseSecretVersion(name string) (SecretVersion, error) {
+	v, err := secretVersionTemplate.parse(name)
+	if err != nil {
+		return SecretVersion{}, err
+	}
+	return SecretVersion{Secret: Secret{Project: v[0], Name: v[1]}, Version: v[2]}, nil
+}
+
+func (s SecretVersion) String() string {
+	return secretVersionTemplate.format(s.Project, s.Name, s.Version)
+}
+
+// Topic is a Pub/Sub topic.
+type Topic struct {
+	Project string
+	Name    string
+}
+
+// ParseTopic parses projects/<project>/topics/<topic>.
+func ParseTopic(name string) (Topic, error) {
+	v, err := topicTemplate.parse(name)
+	if err != nil {
+		return Topic{}, err
+	}
+	return Topic{Project: v[0], Name: v[1]}, nil
+}
+
+func (t Topic) String() string {
+	return topicTemplate.format(t.Project, t.Name)
+}
+
+// Connector is a Serverless VPC Access connector.
+type Connector struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+// ParseConnector parses projects/<project>/locations/<location>/connectors/<connector>.
+func ParseConnector(name string) (Connector, error) {
+	v, err := connectorTemplate.parse(name)
+	if err != nil {
+		return Connector{}, err
+	}
+	return Connector{Project: v[0], Location: v[1], Name: v[2]}, nil
+}
+
+func (c Connector) String() string {
+	return connectorTemplate.format(c.Project, c.Location, c.Name)
+}
+
+// Gateway is a Secure Web Proxy gateway.
+type Gateway struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+// ParseGateway parses projects/<project>/locations/<location>/gateways/<gateway>.
+func ParseGateway(name string) (Gateway, error) {
+	v, err := gatewayTemplate.parse(name)
+	if err != nil {
+		return Gateway{}, err
+	}
+	return Gateway{Project: v[0], Location: v[1], Name: v[2]}, nil
+}
+
+func (g Gateway) String() string {
+	return gatewayTemplate.format(g.Project, g.Location, g.Name)
+}
+
+// GatewaySecurityPolicy is the policy of a Secure Web Proxy gateway.
+type GatewaySecurityPolicy struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+// ParseGatewaySecurityPolicy parses projects/<project>/locations/<location>/gatewaySecurityPolicies/<policy>.
+func ParseGatewaySecurityPolicy(name string) (GatewaySecurityPolicy, error) {
+	v, err := gatewaySecurityPolicyTemplate.parse(name)
+	if err != nil {
+		return GatewaySecurityPolicy{}, err
+	}
+	return GatewaySecurityPolicy{Project: v[0], Location: v[1], Name: v[2]}, nil
+}
+
+func (p GatewaySecurityPolicy) String() string {
+	return gatewaySecurityPolicyTemplate.format(p.Project, p.Location, p.Name)
+}
+
+// URLList is a Secure Web Proxy URL list.
+type URLList struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+// ParseURLList parses projects/<project>/locations/<location>/urlLists/<url list>.
+func ParseURLList(name string) (URLList, error) {
+	v, err := urlListTemplate.parse(name)
+	if err != nil {
+		return URLList{}, err
+	}
+	return URLList{Project: v[0], Location: v[1], Name: v[2]}, nil
+}
+
+func (l URLList) String() string {
+	return urlListTemplate.format(l.Project, l.Location, l.Name)
+}
+
+// ServicePerimeter is a VPC Service Controls perimeter.
+type ServicePerimeter struct {
+	Policy string
+	Name   string
+}
+
+// ParseServicePerimeter parses accessPolicies/<policy>/servicePerimeters/<perimeter>.
+func ParseServicePerimeter(name string) (ServicePerimeter, error) {
+	v, err := servicePerimeterTemplate.parse(name)
+	if err != nil {
+		return ServicePerimeter{}, err
+	}
+	return ServicePerimeter{Policy: v[0], Name: v[1]}, nil
+}
+
+func (p ServicePerimeter) String() string {
+	return servicePerimeterTemplate.format(p.Policy, p.Name)
+}
+
+// AccessLevel is an Access Context Manager access level.
+type AccessLevel struct {
+	Policy string
+	Name   string
+}
+
+// ParseAccessLevel parses accessPolicies/<policy>/accessLevels/<level>.
+func ParseAccessLevel(name string) (AccessLevel, error) {
+	v, err := accessLevelTemplate.parse(name)
+	if err != nil {
+		return AccessLevel{}, err
+	}
+	return AccessLevel{Policy: v[0], Name: v[1]}, nil
+}
+
+func (l AccessLevel) String() string {
+	return accessLevelTemplate.format(l.Policy, l.Name)
+}
+
+// WorkerPool is a Cloud Build private worker pool.
+type WorkerPool struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+// ParseWorkerPool parses projects/<project>/locations/<location>/workerPools/<worker pool>.
+func ParseWorkerPool(name string) (WorkerPool, error) {
+	v, err := workerPoolTemplate.parse(name)
+	if err != nil {
+		return WorkerPool{}, err
+	}
+	return WorkerPool{Project: v[0], Location: v[1], Name: v[2]}, nil
+}
+
+func (p WorkerPool) String() string {
+	return workerPoolTemplate.format(p.Project, p.Location, p.Name)
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcename
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringer[T fmt.Stringer](parse func(string) (T, error)) func(string) (fmt.Stringer, error) {
+	return func(name string) (fmt.Stringer, error) {
+		return parse(name)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) (fmt.Stringer, error)
+		want  fmt.Stringer
+	}{
+		{
+			name:  "projects/prj-security-cf-0f92/locations/us-west1/keyRings/krg-secure-cloud-function/cryptoKeys/key-secure-cloud-function",
+			parse: stringer(ParseCryptoKey),
+			want:  CryptoKey{Project: "prj-security-cf-0f92", Location: "us-west1", KeyRing: "krg-secure-cloud-function", Name: "key-secure-cloud-function"},
+		},
+		{
+			name:  "projects/prj-scf-security-3e57/locations/us-central1/keyRings/krg-secret/cryptoKeys/key-secret/cryptoKeyVersions/1",
+			parse: stringer(ParseCryptoKeyVersion),
+			want:  CryptoKey{Project: "prj-scf-security-3e57", Location: "us-central1", KeyRing: "krg-secret", Name: "key-secret"}.Version("1"),
+		},
+		{
+			name:  "projects/prj-scf-security-3e57/secrets/sct-cloud-function-sql-password",
+			parse: stringer(ParseSecret),
+			want:  Secret{Project: "prj-scf-security-3e57", Name: "sct-cloud-function-sql-password"},
+		},
+		{
+			name:  "projects/prj-scf-security-3e57/secrets/sct-cloud-function-sql-password/versions/1",
+			parse: stringer(ParseSecretVersion),
+			want:  Secret{Project: "prj-scf-security-3e57", Name: "sct-cloud-function-sql-password"}.Version("1"),
+		},
+		{
+			name:  "projects/ci-cloud-functions-5f3e/topics/function2-topic",
+			parse: stringer(ParseTopic),
+			want:  Topic{Project: "ci-cloud-functions-5f3e", Name: "function2-topic"},
+		},
+		{
+			name:  "projects/prj-scf-serverless-6c2d/locations/us-central1/connectors/con-secure-cloud-function",
+			parse: stringer(ParseConnector),
+			want:  Connector{Project: "prj-scf-serverless-6c2d", Location: "us-central1", Name: "con-secure-cloud-function"},
+		},
+		{
+			name:  "projects/prj-restricted-shared-7c1e/locations/us-west1/gateways/secure-web-proxy",
+			parse: stringer(ParseGateway),
+			want:  Gateway{Project: "prj-restricted-shared-7c1e", Location: "us-west1", Name: "secure-web-proxy"},
+		},
+		{
+			name:  "projects/prj-restricted-shared-7c1e/locations/us-west1/gatewaySecurityPolicies/swp-security-policy",
+			parse: stringer(ParseGatewaySecurityPolicy),
+			want:  GatewaySecurityPolicy{Project: "prj-restricted-shared-7c1e", Location: "us-west1", Name: "swp-security-policy"},
+		},
+		{
+			name:  "projects/prj-restricted-shared-7c1e/locations/us-west1/urlLists/swp-url-lists",
+			parse: stringer(ParseURLList),
+			want:  URLList{Project: "prj-restricted-shared-7c1e", Location: "us-west1", Name: "swp-url-lists"},
+		},
+		{
+			name:  "accessPolicies/123456789/servicePerimeters/sp_restricted_cf_8b3e",
+			parse: stringer(ParseServicePerimeter),
+			want:  ServicePerimeter{Policy: "123456789", Name: "sp_restricted_cf_8b3e"},
+		},
+		{
+			name:  "accessPolicies/123456789/accessLevels/alp_restricted_cf_8b3e",
+			parse: stringer(ParseAccessLevel),
+			want:  AccessLevel{Policy: "123456789", Name: "alp_restricted_cf_8b3e"},
+		},
+		{
+			name:  "projects/prj-secure-cloud-function-25de/locations/us-west1/workerPools/workerpool",
+			parse: stringer(ParseWorkerPool),
+			want:  WorkerPool{Project: "prj-secure-cloud-function-25de", Location: "us-west1", Name: "workerpool"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.name, got.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) (fmt.Stringer, error)
+		err   string
+	}{
+		{
+			name:  "key-secure-cloud-function",
+			parse: stringer(ParseCryptoKey),
+			err:   `"key-secure-cloud-function" is not a crypto key name, expected projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}`,
+		},
+		{
+			name:  "projects/p/locations/us-west1/keyRings/k/cryptoKeys/key/cryptoKeyVersions/1",
+			parse: stringer(ParseCryptoKey),
+			err:   "is not a crypto key name",
+		},
+		{
+			name:  "projects/p/locations/us-west1/keyRings/k/cryptoKeys/key/cryptoKeyVersions/latest",
+			parse: stringer(ParseCryptoKeyVersion),
+			err:   "has a version that is not a number",
+		},
+		{
+			name:  "projects/p/topic/function2-topic",
+			parse: stringer(ParseTopic),
+			err:   "is not a topic name",
+		},
+		{
+			name:  "projects//topics/function2-topic",
+			parse: stringer(ParseTopic),
+			err:   `topic name "projects//topics/function2-topic" has an empty project`,
+		},
+		{
+			name:  "projects/p/locations/us-west1/urlLists/swp-url-lists",
+			parse: stringer(ParseGateway),
+			err:   "is not a gateway name",
+		},
+		{
+			name:  "accessPolicies/123/accessLevels/alp",
+			parse: stringer(ParseServicePerimeter),
+			err:   "is not a service perimeter name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.parse(tt.name)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
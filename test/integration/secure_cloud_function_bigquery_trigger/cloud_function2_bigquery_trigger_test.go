@@ -16,71 +16,34 @@ package cloud_function2_bigquery_trigger
 
 import (
 	"fmt"
-	"strings"
+	"net/netip"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
-	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/utils"
 	"github.com/stretchr/testify/assert"
-	"github.com/tidwall/gjson"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/cmek"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/ipplan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/orgpolicy"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/plan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/reachability"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/report"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/resourcename"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/swp"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/vpcsc"
 )
 
-var (
-	RetryableTransientErrors = map[string]string{
-		// Error code 409 for concurrent policy changes.
-		".*Error 409.*There were concurrent policy changes.*": "Concurrent policy changes.",
-
-		// API Rate limit exceeded errors can be retried.
-		".*rateLimitExceeded.*": "Rate limit exceeded.",
-
-		// Project deletion is eventually consistent. Even if google_project resources inside the folder are deleted there may be a deletion error.
-		".*FOLDER_TO_DELETE_NON_EMPTY_VIOLATION.*": "Failed to delete non empty folder.",
-
-		// Granting IAM Roles is eventually consistent.
-		".*Error 403.*Permission.*denied on resource.*": "Permission denied on resource.",
-
-		// Editing VPC Service Controls is eventually consistent.
-		".*Error 403.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*":    "Request is prohibited by organization's policy.",
-		".*Error code 7.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*": "Request is prohibited by organization's policy.",
-
-		// Google Storage Service Agent propagation issue.
-		".*Error 400.*Service account service-.*@gs-project-accounts.iam.gserviceaccount.com does not exist.*": "Google Storage Service Agent propagation issue",
-	}
-)
-
-type Protocols struct {
-	Protocol string
-	Ports    []string
-}
-
-func GetLastSplitElement(value string, sep string) string {
-	splitted := strings.Split(value, sep)
-	return splitted[len(splitted)-1]
-}
-
-func GetResultFieldStrSlice(rs []gjson.Result, field string) []string {
-	s := make([]string, 0)
-	for _, r := range rs {
-		s = append(s, r.Get(field).String())
-	}
-	return s
-}
-
-// GetOrgACMPolicyID gets the Organization Access Context Manager Policy ID
-func GetOrgACMPolicyID(t testing.TB, orgID string) string {
-	filter := fmt.Sprintf("parent:organizations/%s", orgID)
-	id := gcloud.Runf(t, "access-context-manager policies list --organization %s --filter %s --quiet", orgID, filter).Array()
-	if len(id) == 0 {
-		return ""
-	}
-	return GetLastSplitElement(id[0].Get("name").String(), "/")
-}
+const fixtureDir = "testdata"
 
 func TestGCF2BigqueryTrigger(t *testing.T) {
-	orgID := utils.ValFromEnv(t, "TF_VAR_org_id")
-	policyID := GetOrgACMPolicyID(t, orgID)
+	gc := testutils.NewGCloud(t, testutils.FixturePath(fixtureDir))
+	orgID := gc.ValFromEnv(t, "TF_VAR_org_id")
+	policyID := gc.GetOrgACMPolicyID(t, orgID)
 	createACM := false
 
 	vars := map[string]interface{}{
@@ -95,222 +58,133 @@ func TestGCF2BigqueryTrigger(t *testing.T) {
 		}
 	}
 
-	restrictedServices := []string{
-		"accessapproval.googleapis.com",
-		"adsdatahub.googleapis.com",
-		"aiplatform.googleapis.com",
-		"alloydb.googleapis.com",
-		"analyticshub.googleapis.com",
-		"apigee.googleapis.com",
-		"apigeeconnect.googleapis.com",
-		"artifactregistry.googleapis.com",
-		"assuredworkloads.googleapis.com",
-		"automl.googleapis.com",
-		"baremetalsolution.googleapis.com",
-		"batch.googleapis.com",
-		"bigquery.googleapis.com",
-		"bigquerydatapolicy.googleapis.com",
-		"bigquerydatatransfer.googleapis.com",
-		"bigquerymigration.googleapis.com",
-		"bigqueryreservation.googleapis.com",
-		"bigtable.googleapis.com",
-		"binaryauthorization.googleapis.com",
-		"cloud.googleapis.com",
-		"cloudasset.googleapis.com",
-		"cloudbuild.googleapis.com",
-		"clouddebugger.googleapis.com",
-		"clouddeploy.googleapis.com",
-		"clouderrorreporting.googleapis.com",
-		"cloudfunctions.googleapis.com",
-		"cloudkms.googleapis.com",
-		"cloudprofiler.googleapis.com",
-		"cloudresourcemanager.googleapis.com",
-		"cloudscheduler.googleapis.com",
-		"cloudsearch.googleapis.com",
-		"cloudtrace.googleapis.com",
-		"composer.googleapis.com",
-		"compute.googleapis.com",
-		"connectgateway.googleapis.com",
-		"contactcenterinsights.googleapis.com",
-		"container.googleapis.com",
-		"containeranalysis.googleapis.com",
-		"containerfilesystem.googleapis.com",
-		"containerregistry.googleapis.com",
-		"containerthreatdetection.googleapis.com",
-		"datacatalog.googleapis.com",
-		"dataflow.googleapis.com",
-		"datafusion.googleapis.com",
-		"datamigration.googleapis.com",
-		"dataplex.googleapis.com",
-		"dataproc.googleapis.com",
-		"datastream.googleapis.com",
-		"dialogflow.googleapis.com",
-		"dlp.googleapis.com",
-		"dns.googleapis.com",
-		"documentai.googleapis.com",
-		"domains.googleapis.com",
-		"eventarc.googleapis.com",
-		"file.googleapis.com",
-		"firebaseappcheck.googleapis.com",
-		"firebaserules.googleapis.com",
-		"firestore.googleapis.com",
-		"gameservices.googleapis.com",
-		"gkebackup.googleapis.com",
-		"gkeconnect.googleapis.com",
-		"gkehub.googleapis.com",
-		"healthcare.googleapis.com",
-		"iam.googleapis.com",
-		"iamcredentials.googleapis.com",
-		"iaptunnel.googleapis.com",
-		"ids.googleapis.com",
-		"integrations.googleapis.com",
-		"kmsinventory.googleapis.com",
-		"krmapihosting.googleapis.com",
-		"language.googleapis.com",
-		"lifesciences.googleapis.com",
-		"logging.googleapis.com",
-		"managedidentities.googleapis.com",
-		"memcache.googleapis.com",
-		"meshca.googleapis.com",
-		"meshconfig.googleapis.com",
-		"metastore.googleapis.com",
-		"ml.googleapis.com",
-		"monitoring.googleapis.com",
-		"networkconnectivity.googleapis.com",
-		"networkmanagement.googleapis.com",
-		"networksecurity.googleapis.com",
-		"networkservices.googleapis.com",
-		"notebooks.googleapis.com",
-		"opsconfigmonitoring.googleapis.com",
-		"orgpolicy.googleapis.com",
-		"osconfig.googleapis.com",
-		"oslogin.googleapis.com",
-		"privateca.googleapis.com",
-		"pubsub.googleapis.com",
-		"pubsublite.googleapis.com",
-		"recaptchaenterprise.googleapis.com",
-		"recommender.googleapis.com",
-		"redis.googleapis.com",
-		"retail.googleapis.com",
-		"run.googleapis.com",
-		"secretmanager.googleapis.com",
-		"servicecontrol.googleapis.com",
-		"servicedirectory.googleapis.com",
-		"spanner.googleapis.com",
-		"speakerid.googleapis.com",
-		"speech.googleapis.com",
-		"sqladmin.googleapis.com",
-		"storage.googleapis.com",
-		"storagetransfer.googleapis.com",
-		"sts.googleapis.com",
-		"texttospeech.googleapis.com",
-		"timeseriesinsights.googleapis.com",
-		"tpu.googleapis.com",
-		"trafficdirector.googleapis.com",
-		"transcoder.googleapis.com",
-		"translate.googleapis.com",
-		"videointelligence.googleapis.com",
-		"vision.googleapis.com",
-		"visionai.googleapis.com",
-		"vmmigration.googleapis.com",
-		"vpcaccess.googleapis.com",
-		"webrisk.googleapis.com",
-		"workflows.googleapis.com",
-		"workstations.googleapis.com",
-	}
-
 	bqt := tft.NewTFBlueprintTest(t,
 		tft.WithVars(vars),
-		tft.WithRetryableTerraformErrors(RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithRetryableTerraformErrors(testutils.RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithLogger(testutils.NewTransientErrorClassifier().Logger(t, utils.GetLoggerFromT())),
 	)
 
 	bqt.DefineVerify(func(assert *assert.Assertions) {
-		// Removing DefaultVerify because Cloud Function API is changing the build_config/source/storage_source/generation and this modification is breaking the build validation.
-		// bqt.DefaultVerify(assert)
-
-		location := "us-west1"
-		name := bqt.GetStringOutput("cloud_function_name")
-		projectID := bqt.GetStringOutput("serverless_project_id")
-		securityProjectID := bqt.GetStringOutput("security_project_id")
-		serverlessProjectNumber := bqt.GetStringOutput("serverless_project_number")
-		networkProjectID := bqt.GetStringOutput("network_project_id")
-		connectorID := bqt.GetStringOutput("connector_id")
-		saEmail := bqt.GetStringOutput("service_account_email")
-		networkName := bqt.GetStringOutput("service_vpc_name")
-		servicePerimeterLink := fmt.Sprintf("accessPolicies/%s/servicePerimeters/%s", policyID, bqt.GetStringOutput("restricted_service_perimeter_name"))
-		accessLevel := fmt.Sprintf("accessPolicies/%s/accessLevels/%s", policyID, bqt.GetStringOutput("restricted_access_level_name"))
-		cfKMSKey := fmt.Sprintf("projects/%s/locations/%s/keyRings/krg-secure-cloud-function/cryptoKeys/key-secure-cloud-function", securityProjectID, location)
-
-		// VPC-SC Tests
-		servicePerimeter := gcloud.Runf(t, "access-context-manager perimeters describe %s --policy %s", servicePerimeterLink, policyID)
+		plan.DefaultVerify(assert, bqt, plan.VolatileAttributes)
+
+		verify(t, gc, gc.Outputs(t, bqt.GetStringOutput), policyID)
+	})
+	bqt.Test()
+}
+
+// TestGCF2BigqueryTriggerReplay runs the verify stage against the gcloud fixture recorded in each location.
+func TestGCF2BigqueryTriggerReplay(t *testing.T) {
+	for _, fixture := range testutils.Fixtures(t, fixtureDir) {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			gc := testutils.NewReplayGCloud(t, fixture)
+			policyID := gc.GetOrgACMPolicyID(t, gc.ValFromEnv(t, "TF_VAR_org_id"))
+			verify(t, gc, gc.Outputs(t, nil), policyID)
+		})
+	}
+}
+
+func verify(t testing.TB, gc *testutils.GCloud, output func(string) string, policyID string) {
+	location := output("location")
+	name := output("cloud_function_name")
+	projectID := output("serverless_project_id")
+	securityProjectID := output("security_project_id")
+	serverlessProjectNumber := output("serverless_project_number")
+	networkProjectID := output("network_project_id")
+	connectorID := output("connector_id")
+	saEmail := output("service_account_email")
+	networkName := output("service_vpc_name")
+	servicePerimeterLink := resourcename.ServicePerimeter{Policy: policyID, Name: output("restricted_service_perimeter_name")}.String()
+	accessLevel := resourcename.AccessLevel{Policy: policyID, Name: output("restricted_access_level_name")}.String()
+	cfKMSKey := resourcename.CryptoKey{Project: securityProjectID, Location: location, KeyRing: "krg-secure-cloud-function", Name: "key-secure-cloud-function"}.String()
+
+	rep := report.New(t)
+
+	// VPC-SC Tests
+	servicePerimeter := gc.Runf(t, "access-context-manager perimeters describe %s --policy %s", servicePerimeterLink, policyID)
+	rep.Check(report.Perimeter, servicePerimeterLink, func(assert *report.Assertions) {
 		assert.Equal(servicePerimeterLink, servicePerimeter.Get("name").String(), fmt.Sprintf("service perimeter %s should exist", servicePerimeterLink))
 		listLevels := utils.GetResultStrSlice(servicePerimeter.Get("status.accessLevels").Array())
 		assert.Contains(listLevels, accessLevel, fmt.Sprintf("service perimeter %s should have access level %s", servicePerimeterLink, accessLevel))
 		listServices := utils.GetResultStrSlice(servicePerimeter.Get("status.restrictedServices").Array())
-		assert.Subset(listServices, restrictedServices, fmt.Sprintf("service perimeter %s should restrict %v", servicePerimeterLink, restrictedServices))
+		catalog := vpcsc.Default()
+		services := catalog.Compare(listServices)
+		assert.Empty(services.Missing, fmt.Sprintf("service perimeter %s should restrict every service of restricted services catalog version %d", servicePerimeterLink, catalog.Version))
+		for _, l := range services.Lines() {
+			t.Logf("service perimeter %s: %s", servicePerimeterLink, l)
+		}
+	})
 
-		// Network test
-		opNet := gcloud.Runf(t, "compute networks describe %s --project=%s", networkName, networkProjectID)
+	// Network test
+	opNet := gc.Runf(t, "compute networks describe %s --project=%s", networkName, networkProjectID)
+	subnetName := output("service_vpc_subnet_name")
+	subNetRange := "10.0.0.0/28"
+	subnet := gc.Runf(t, "compute networks subnets describe %s --region %s --project %s", subnetName, location, networkProjectID)
+	subnetProxyName := fmt.Sprintf("sb-swp-%s", location)
+	subnetProxyRange := "10.129.0.0/23"
+	subnetProxy := gc.Runf(t, "compute networks subnets describe %s --region %s --project %s", subnetProxyName, location, networkProjectID)
+	rep.Check(report.Network, opNet.Get("selfLink").String(), func(assert *report.Assertions) {
 		assert.Equal("GLOBAL", opNet.Get("routingConfig.routingMode").String(), "Routing Mode should be GLOBAL.")
 
 		// Sub-network test
-		subnetName := bqt.GetStringOutput("service_vpc_subnet_name")
-		subNetRange := "10.0.0.0/28"
-		subnet := gcloud.Runf(t, "compute networks subnets describe %s --region %s --project %s", subnetName, location, networkProjectID)
 		assert.Equal(subnetName, subnet.Get("name").String(), fmt.Sprintf("subnet %s should exist", subnetName))
 		assert.Equal(subNetRange, subnet.Get("ipCidrRange").String(), fmt.Sprintf("IP CIDR range %s should be", subNetRange))
 
 		// Sub-network Proxy test
-		subnetProxyName := fmt.Sprintf("sb-swp-%s", location)
-		subnetProxyRange := "10.129.0.0/23"
-		subnetProxy := gcloud.Runf(t, "compute networks subnets describe %s --region %s --project %s", subnetProxyName, location, networkProjectID)
 		assert.Equal(subnetProxyName, subnetProxy.Get("name").String(), fmt.Sprintf("Subnet %s should exist", subnetProxyName))
 		assert.Equal(subnetProxyRange, subnetProxy.Get("ipCidrRange").String(), fmt.Sprintf("IP CIDR range %s should be", subnetProxyRange))
+	})
 
-		// Firewall - Deny all egress test
-		denyAllEgressName := "fw-e-shared-restricted-65535-e-d-all-all-all"
-		denyAllEgressRule := gcloud.Runf(t, "compute firewall-rules describe %s --project %s", denyAllEgressName, networkProjectID)
-		assert.Equal(denyAllEgressName, denyAllEgressRule.Get("name").String(), fmt.Sprintf("firewall rule %s should exist", denyAllEgressName))
-		assert.Equal("EGRESS", denyAllEgressRule.Get("direction").String(), fmt.Sprintf("firewall rule %s direction should be EGRESS", denyAllEgressName))
-		assert.True(denyAllEgressRule.Get("logConfig.enable").Bool(), fmt.Sprintf("firewall rule %s should have log configuration enabled", denyAllEgressName))
-		assert.Equal("0.0.0.0/0", denyAllEgressRule.Get("destinationRanges").Array()[0].String(), fmt.Sprintf("firewall rule %s destination ranges should be 0.0.0.0/0", denyAllEgressName))
-		assert.Equal(1, len(denyAllEgressRule.Get("denied").Array()), fmt.Sprintf("firewall rule %s should have only one denied", denyAllEgressName))
-		assert.Equal(1, len(denyAllEgressRule.Get("denied.0").Map()), fmt.Sprintf("firewall rule %s should have only one denied only with no ports", denyAllEgressName))
-		assert.Equal("all", denyAllEgressRule.Get("denied.0.IPProtocol").String(), fmt.Sprintf("firewall rule %s should deny all protocols", denyAllEgressName))
-
-		// Firewall - Allow Restricted APIs
-		allowApiEgressName := "fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443"
-		allowApiEgressRule := gcloud.Runf(t, "compute firewall-rules describe %s --project %s", allowApiEgressName, networkProjectID)
-		assert.Equal(allowApiEgressName, allowApiEgressRule.Get("name").String(), fmt.Sprintf("firewall rule %s should exist", allowApiEgressName))
-		assert.Equal("EGRESS", allowApiEgressRule.Get("direction").String(), fmt.Sprintf("firewall rule %s direction should be EGRESS", allowApiEgressName))
-		assert.True(allowApiEgressRule.Get("logConfig.enable").Bool(), fmt.Sprintf("firewall rule %s should have log configuration enabled", allowApiEgressName))
-		assert.Equal("10.3.0.5", allowApiEgressRule.Get("destinationRanges").Array()[0].String(), fmt.Sprintf("firewall rule %s destination ranges should be %s", allowApiEgressName, subNetRange))
-		assert.Equal(1, len(allowApiEgressRule.Get("allowed").Array()), fmt.Sprintf("firewall rule %s should have only one allowed", allowApiEgressName))
-		assert.Equal(2, len(allowApiEgressRule.Get("allowed.0").Map()), fmt.Sprintf("firewall rule %s should have only one allowed only with protocol end ports", allowApiEgressName))
-		assert.Equal("tcp", allowApiEgressRule.Get("allowed.0.IPProtocol").String(), fmt.Sprintf("firewall rule %s should allow tcp protocol", allowApiEgressName))
-		assert.Equal(1, len(allowApiEgressRule.Get("allowed.0.ports").Array()), fmt.Sprintf("firewall rule %s should allow only one port", allowApiEgressName))
-		assert.Equal("443", allowApiEgressRule.Get("allowed.0.ports.0").String(), fmt.Sprintf("firewall rule %s should allow port 443", allowApiEgressName))
-
-		// Firewall - Allow egress to Secure Web Proxy
-		allowSwpEgressName := "fw-allow-tcp-443-egress-to-secure-web-proxy"
-		swpRanges := []string{subnetProxyRange, subNetRange}
-		allowSwpEgressRule := gcloud.Runf(t, "compute firewall-rules describe %s --project %s", allowSwpEgressName, networkProjectID)
-		assert.Equal(allowSwpEgressName, allowSwpEgressRule.Get("name").String(), fmt.Sprintf("firewall rule %s should exist", allowSwpEgressName))
-		assert.Equal("EGRESS", allowSwpEgressRule.Get("direction").String(), fmt.Sprintf("firewall rule %s direction should be EGRESS", allowSwpEgressName))
-		assert.True(allowSwpEgressRule.Get("logConfig.enable").Bool(), fmt.Sprintf("firewall rule %s should have log configuration enabled", allowSwpEgressName))
-		assert.Equal(1, len(allowSwpEgressRule.Get("allowed").Array()), fmt.Sprintf("firewall rule %s should have only one allowed", allowSwpEgressName))
-		assert.Equal(2, len(allowSwpEgressRule.Get("allowed.0").Map()), fmt.Sprintf("firewall rule %s should have only one protocol and ports", allowSwpEgressName))
-		assert.Equal("tcp", allowSwpEgressRule.Get("allowed.0.IPProtocol").String(), fmt.Sprintf("firewall rule %s should allow tcp protocol", allowSwpEgressName))
-		assert.Equal(1, len(allowSwpEgressRule.Get("allowed.0.ports").Array()), fmt.Sprintf("firewall rule %s should allow only one port", allowSwpEgressName))
-		assert.Equal("443", allowSwpEgressRule.Get("allowed.0.ports.0").String(), fmt.Sprintf("firewall rule %s should allow port 443", allowSwpEgressName))
-		firewallDestinationRanges := utils.GetResultStrSlice(allowSwpEgressRule.Get("destinationRanges").Array())
-		assert.Subset(swpRanges, firewallDestinationRanges, fmt.Sprintf("firewall rule %s destination ranges should be %v", allowSwpEgressName, swpRanges))
-
-		// VPC test
-		connectorName := "con-secure-cloud-function"
-		expectedSubnet := fmt.Sprintf("sb-restricted-%s", location)
-		expectedMachineType := "e2-micro"
-		opVPCConnector := gcloud.Runf(t, "compute networks vpc-access connectors describe %s --region=%s --project=%s", connectorName, location, projectID)
+	// Firewall tests
+	allowApiAddress := "10.3.0.5"
+	rep.Check(report.FirewallRules, networkProjectID, func(assert *report.Assertions) {
+		gc.AssertFirewallRules(t, assert, networkProjectID,
+			testutils.FirewallExpectation{
+				Name:              "fw-e-shared-restricted-65535-e-d-all-all-all",
+				Direction:         "EGRESS",
+				LogEnabled:        true,
+				DestinationRanges: []string{"0.0.0.0/0"},
+				Denied:            []testutils.Protocols{{Protocol: "all"}},
+				NamingConvention:  true,
+			},
+			testutils.FirewallExpectation{
+				Name:              "fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443",
+				Direction:         "EGRESS",
+				LogEnabled:        true,
+				DestinationRanges: []string{allowApiAddress},
+				Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"443"}}},
+				NamingConvention:  true,
+			},
+			testutils.FirewallExpectation{
+				Name:              "fw-allow-tcp-443-egress-to-secure-web-proxy",
+				Direction:         "EGRESS",
+				LogEnabled:        true,
+				DestinationRanges: []string{subnetProxyRange, subNetRange},
+				Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"443"}}},
+			},
+		)
+	})
+
+	// Egress reachability test
+	rep.Check(report.EgressReachability, connectorID, func(assert *report.Assertions) {
+		connectorName, err := resourcename.ParseConnector(connectorID)
+		if !assert.NoError(err) {
+			return
+		}
+		sim, err := reachability.New(gc.ListFirewallRules(t, networkProjectID, networkName))
+		if assert.NoError(err) {
+			connector := reachability.ConnectorInstance(connectorName.Location, connectorName.Name)
+			swpAddress := netip.MustParseAddr("10.0.0.10")
+			assert.Equal([]reachability.PortRange{{From: 443, To: 443}}, sim.AllowedPorts(connector, swpAddress, "tcp"), fmt.Sprintf("connector should reach the Secure Web Proxy %s only on port 443", swpAddress))
+			assert.Empty(sim.AllowedPorts(connector, netip.MustParseAddr("8.8.8.8"), "tcp"), "connector should not reach the internet")
+		}
+	})
+
+	// VPC test
+	connectorName := "con-secure-cloud-function"
+	expectedSubnet := fmt.Sprintf("sb-restricted-%s", location)
+	expectedMachineType := "e2-micro"
+	opVPCConnector := gc.Runf(t, "compute networks vpc-access connectors describe %s --region=%s --project=%s", connectorName, location, projectID)
+	rep.Check(report.VPCConnector, connectorID, func(assert *report.Assertions) {
 		assert.Equal(connectorID, opVPCConnector.Get("name").String(), fmt.Sprintf("Should have same id: %s", connectorID))
 		assert.Equal(expectedSubnet, opVPCConnector.Get("subnet.name").String(), fmt.Sprintf("Should have same subnetwork: %s", expectedSubnet))
 		assert.Equal(expectedMachineType, opVPCConnector.Get("machineType").String(), fmt.Sprintf("Should have same machineType: %s", expectedMachineType))
@@ -318,128 +192,197 @@ func TestGCF2BigqueryTrigger(t *testing.T) {
 		assert.Equal("2", opVPCConnector.Get("minInstances").String(), "Should have minInstances equals to 2")
 		assert.Equal("1000", opVPCConnector.Get("maxThroughput").String(), "Should have maxThroughput equals to 1000")
 		assert.Equal("200", opVPCConnector.Get("minThroughput").String(), "Should have minThroughput equals to 200")
+	})
 
-		// Org Policy test
-		for _, orgPolicy := range []struct {
-			constraint    string
-			allowedValues string
-		}{
-			{
-				constraint:    "constraints/cloudfunctions.allowedIngressSettings",
-				allowedValues: "ALLOW_INTERNAL_ONLY",
-			},
-			{
-				constraint:    "cloudfunctions.allowedVpcConnectorEgressSettings",
-				allowedValues: "ALL_TRAFFIC",
-			},
-			{
-				constraint:    "constraints/run.allowedVPCEgress",
-				allowedValues: "all-traffic",
-			},
-			{
-				constraint:    "constraints/run.allowedIngress",
-				allowedValues: "is:internal-and-cloud-load-balancing",
-			},
-		} {
-			opOrgPolicies := gcloud.Runf(t, "resource-manager org-policies describe %s --project=%s --flatten listPolicy.allowedValues[]", orgPolicy.constraint, projectID).Array()
-			assert.Equal(orgPolicy.allowedValues, opOrgPolicies[0].Get("listPolicy.allowedValues").String(), fmt.Sprintf("Constraint %s should have policy %s", orgPolicy.constraint, orgPolicy.allowedValues))
+	// Org Policy test
+	rep.Check(report.OrgPolicies, "projects/"+projectID, func(assert *report.Assertions) {
+		orgPolicies, err := orgpolicy.ExpectationsFromTerraform("../../../modules/secure-cloud-function-security/org_policies.tf")
+		if assert.NoError(err) {
+			orgPolicyReport := orgpolicy.Verify("projects/"+projectID, orgPolicies, func(constraint string) (orgpolicy.Policy, error) {
+				return orgpolicy.Parse([]byte(gc.Runf(t, "org-policies describe %s --project %s --effective", orgpolicy.ShortName(constraint), projectID).Raw))
+			})
+			for _, f := range orgPolicyReport.Findings {
+				assert.Fail("org policy", f.String())
+			}
 		}
+	})
 
-		reqVPCCon := "constraints/cloudfunctions.requireVPCConnector"
-		opOrgPolBool := gcloud.Runf(t, "resource-manager org-policies describe %s --project=%s", reqVPCCon, projectID)
-		assert.Equal("true", opOrgPolBool.Get("booleanPolicy.enforced").String(), fmt.Sprintf("Constraint %s should be enforced.", reqVPCCon))
-
-		// Service account test
-		cfSaName := "sa-cloud-function"
-		serviceAccountEmail := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", cfSaName, projectID)
-		serviceAccountID := fmt.Sprintf("projects/%s/serviceAccounts/%s", projectID, serviceAccountEmail)
-		serviceAccount := gcloud.Runf(t, "iam service-accounts describe %s", serviceAccountEmail)
+	// Service account test
+	cfSaName := "sa-cloud-function"
+	serviceAccountEmail := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", cfSaName, projectID)
+	serviceAccountID := fmt.Sprintf("projects/%s/serviceAccounts/%s", projectID, serviceAccountEmail)
+	serviceAccount := gc.Runf(t, "iam service-accounts describe %s", serviceAccountEmail)
+	rep.Check(report.FunctionIdentity, serviceAccountID, func(assert *report.Assertions) {
 		assert.Equal(serviceAccountID, serviceAccount.Get("name").String(), fmt.Sprintf("Service Account %s should exist", serviceAccountID))
+	})
 
-		// Workerpool testresource-manager org-policies describe
-		workerPoolName := "workerpool"
-		opWorkerPool := gcloud.Runf(t, "builds worker-pools describe %s --project %s --region %s", workerPoolName, projectID, location)
+	// Workerpool test
+	workerPool := resourcename.WorkerPool{Project: projectID, Location: location, Name: "workerpool"}
+	opWorkerPool := gc.Runf(t, "builds worker-pools describe %s --project %s --region %s", workerPool.Name, projectID, location)
+	rep.Check(report.PrivateWorkerPool, workerPool.String(), func(assert *report.Assertions) {
+		assert.Equal(workerPool.String(), opWorkerPool.Get("name").String(), fmt.Sprintf("Worker pool %s should exist", workerPool))
 		assert.Equal("NO_PUBLIC_EGRESS", opWorkerPool.Get("privatePoolV1Config.networkConfig.egressOption").String(), "Private Pool config should have NO_PUBLIC_EGRESS")
+	})
 
-		// Cloud Function test
-		cf := gcloud.Runf(t, "functions describe %s --project %s --gen2 --region %s", name, projectID, location)
-		assert.Equal("ACTIVE", cf.Get("state").String(), "Should be ACTIVE. Cloud Function is not successfully deployed.")
-		assert.Equal(connectorID, cf.Get("serviceConfig.vpcConnector").String(), fmt.Sprintf("VPC Connector should be %s. Connector was not set.", connectorID))
-		assert.Equal("ALL_TRAFFIC", cf.Get("serviceConfig.vpcConnectorEgressSettings").String(), "Egress setting should be ALL_TRAFFIC.")
-		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.Get("serviceConfig.ingressSettings").String(), "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
-		assert.Equal(saEmail, cf.Get("serviceConfig.serviceAccountEmail").String(), fmt.Sprintf("Cloud Function should use the service account %s.", saEmail))
-		assert.Contains(cf.Get("eventTrigger.eventType").String(), "google.cloud.audit.log.v1.written", "Event Trigger is not based on Audit Logs. Check the EventType configuration.")
-
-		// Cloud Function Storage Bucket test
-		bucketSrcBucket := fmt.Sprintf("gcf-v2-sources-%s-%s", serverlessProjectNumber, location)
-		bktArgs := gcloud.WithCommonArgs([]string{"--project", projectID, "--json"})
-		opSrcBucket := gcloud.Run(t, fmt.Sprintf("alpha storage ls --buckets gs://%s", bucketSrcBucket), bktArgs).Array()
-		assert.Equal(cfKMSKey, opSrcBucket[0].Get("metadata.encryption.defaultKmsKeyName").String(), fmt.Sprintf("Should have same KMS key: %s", cfKMSKey))
-		assert.Equal("true", opSrcBucket[0].Get("metadata.iamConfiguration.bucketPolicyOnly.enabled").String(), "Should have Bucket Policy Only enabled.")
+	// Cloud Function test
+	cf := gc.DescribeCloudFunction(t, name, projectID, location)
+	arCF := fmt.Sprintf("rep-cloud-function-%s", name)
+	opAR := gc.Runf(t, "artifacts repositories describe %s --project %s --location %s", arCF, projectID, location)
+	repo, err := audit.ParseArtifactRepository([]byte(opAR.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := audit.Baseline{VPCConnector: connectorID, ServiceAccountEmail: saEmail, Repository: &repo, KMSKey: cfKMSKey}
+	violations := audit.Audit(cf, baseline)
+	rep.Check(report.FunctionActive, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ACTIVE", cf.State, "Should be ACTIVE. Cloud Function is not successfully deployed.")
+	})
+	rep.Check(report.FunctionIngress, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.ServiceConfig.IngressSettings, "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
+		report.AssertNoViolations(assert, violations, audit.RuleInternalIngress)
+	})
+	rep.Check(report.FunctionEgress, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleVPCConnector, audit.RuleEgressAllTraffic)
+	})
+	rep.Check(report.PrivateWorkerPool, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RulePrivateWorkerPool)
+		assert.Equal(workerPool.String(), cf.BuildConfig.WorkerPool, fmt.Sprintf("Build should run in worker pool %s", workerPool))
+	})
+	rep.Check(report.FunctionIdentity, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleDedicatedSA)
+	})
+	rep.Check(report.FunctionTrigger, cf.Name, func(assert *report.Assertions) {
+		if assert.NotNil(cf.EventTrigger, "Event Trigger should exist.") {
+			assert.Contains(cf.EventTrigger.EventType, "google.cloud.audit.log.v1.written", "Event Trigger is not based on Audit Logs. Check the EventType configuration.")
+		}
+	})
 
-		// Cloud Function Artifact Registry
-		arCF := fmt.Sprintf("rep-cloud-function-%s", name)
-		opAR := gcloud.Runf(t, "artifacts repositories describe %s --project %s --location %s", arCF, projectID, location)
+	// Cloud Function Storage Bucket, Artifact Registry and EventArc test
+	bucketSrcBucket := fmt.Sprintf("gcf-v2-sources-%s-%s", serverlessProjectNumber, location)
+	bktArgs := []string{"--project", projectID, "--json"}
+	opSrcBucket := gc.Run(t, fmt.Sprintf("alpha storage ls --buckets gs://%s", bucketSrcBucket), bktArgs).Array()
+	opEventArc := gc.Runf(t, "eventarc google-channels describe --project %s --location %s", projectID, location)
+	rep.Check(report.FunctionCMEK, cf.Name, func(assert *report.Assertions) {
+		report.AssertCMEK(assert, baseline, violations)
+		assert.Equal(cfKMSKey, opSrcBucket[0].Get("metadata.encryption.defaultKmsKeyName").String(), fmt.Sprintf("Should have same KMS key: %s", cfKMSKey))
 		assert.Equal(cfKMSKey, opAR.Get("kmsKeyName").String(), fmt.Sprintf("Should have KMS Key: %s", cfKMSKey))
-		assert.Equal("DOCKER", opAR.Get("format").String(), "Should have type: DOCKER")
-
-		// Cloud Function EventArc
-		opEventArc := gcloud.Runf(t, "eventarc google-channels describe --project %s --location %s", projectID, location)
 		assert.Equal(cfKMSKey, opEventArc.Get("cryptoKeyName").String(), fmt.Sprintf("Should have KMS Key: %s", cfKMSKey))
+	})
+	cmekUsages := slices.Concat(
+		cmek.Collect(bucketSrcBucket, "Storage bucket", opSrcBucket[0].Get("metadata.location").String(), opSrcBucket[0], cmek.ServiceAgent(cmek.ServiceStorage, serverlessProjectNumber)),
+		cmek.Collect(opAR.Get("name").String(), "Artifact Registry repository", location, opAR, cmek.ServiceAgent(cmek.ServiceArtifactRegistry, serverlessProjectNumber)),
+		cmek.Collect(opEventArc.Get("name").String(), "Eventarc channel", location, opEventArc, cmek.ServiceAgent(cmek.ServiceEventarc, serverlessProjectNumber)),
+	)
+	rep.Check(report.Resources, bucketSrcBucket, func(assert *report.Assertions) {
+		assert.Equal("true", opSrcBucket[0].Get("metadata.iamConfiguration.bucketPolicyOnly.enabled").String(), "Should have Bucket Policy Only enabled.")
+		assert.Equal("DOCKER", opAR.Get("format").String(), "Should have type: DOCKER")
+	})
 
-		// Bigquery test
-		bqKmsKey := bqt.GetStringOutput("bigquery_kms_key")
-		opDataset := gcloud.Runf(t, "alpha bq tables describe tbl_test --dataset dst_secure_cloud_function --project %s", projectID)
-		fullTablePath := fmt.Sprintf("%s:dst_secure_cloud_function.tbl_test", projectID)
+	// Bigquery test
+	bqKmsKey := output("bigquery_kms_key")
+	opDataset := gc.Runf(t, "alpha bq tables describe tbl_test --dataset dst_secure_cloud_function --project %s", projectID)
+	fullTablePath := fmt.Sprintf("%s:dst_secure_cloud_function.tbl_test", projectID)
+	rep.Check(report.DataCMEK, fullTablePath, func(assert *report.Assertions) {
 		assert.Equal(fullTablePath, opDataset.Get("id").String(), fmt.Sprintf("Should have same id: %s", fullTablePath))
 		assert.Equal(location, opDataset.Get("location").String(), fmt.Sprintf("Should have same location: %s", location))
 		assert.Equal(bqKmsKey, opDataset.Get("encryptionConfiguration.kmsKeyName").String(), fmt.Sprintf("Should have the KMS Key: %s", bqKmsKey))
+	})
 
-		// Global Address test
-		// Networking Connection Peering test
-		opNetworkPeering := gcloud.Runf(t, "compute networks peerings list --network=%s --project=%s", networkName, networkProjectID).Array()
+	cmekUsages = append(cmekUsages, cmek.Collect(fullTablePath, "BigQuery table", opDataset.Get("location").String(), opDataset, cmek.ServiceAgent(cmek.ServiceBigQuery, serverlessProjectNumber))...)
+
+	// CMEK coverage test
+	describeKey := func(key string) (cmek.Key, error) {
+		return cmek.ParseKey([]byte(gc.Runf(t, "kms keys describe %s", key).Raw), []byte(gc.Runf(t, "kms keys get-iam-policy %s", key).Raw))
+	}
+	rep.Check(report.CMEKCoverage, projectID, func(assert *report.Assertions) {
+		kmsExpectations, err := cmek.ExpectationsFromTerraform("../../../examples/secure_cloud_function_bigquery_trigger/main.tf")
+		if !assert.NoError(err) {
+			return
+		}
+		table, err := cmek.Verify(cmekUsages, describeKey, kmsExpectations)
+		if !assert.NoError(err) {
+			return
+		}
+		t.Logf("CMEK coverage:\n%s", table)
+		for _, f := range table.Findings() {
+			assert.Fail("CMEK key is not consistent with the resource it encrypts.", f.String())
+		}
+	})
+
+	// Networking Connection Peering test
+	opNetworkPeering := gc.Runf(t, "compute networks peerings list --network=%s --project=%s", networkName, networkProjectID).Array()
+	rep.Check(report.NetworkPeering, networkName, func(assert *report.Assertions) {
 		assert.Equal(1, len(opNetworkPeering), "Should have only one Network Peering.")
+	})
 
-		// Gateway Security Policy test
-		opSwpPolicy := gcloud.Runf(t, "network-security gateway-security-policies list --location=%s --project=%s", location, networkProjectID).Array()
+	// Gateway Security Policy test
+	opSwpPolicy := gc.Runf(t, "network-security gateway-security-policies list --location=%s --project=%s", location, networkProjectID).Array()
+	swpURLListValues := []string{
+		"*google.com/go*",
+		"*github.com/GoogleCloudPlatform*",
+		"*github.com/cloudevents*",
+		"*golang.org/x*",
+		"*google.golang.org/*",
+		"*github.com/golang/*",
+		"*github.com/google/*",
+		"*github.com/googleapis/*",
+		"*github.com/json-iterator/go",
+		"*github.com/modern-go/concurrent",
+		"*github.com/modern-go/reflect2",
+		"*go.opencensus.io",
+		"*go.uber.org/atomic",
+		"*go.uber.org/multierr",
+		"*go.uber.org/zap",
+	}
+	opSwpUrlList := gc.Runf(t, "network-security url-lists list --location=%s --project=%s", location, networkProjectID).Array()
+	swpURLListName := resourcename.URLList{Project: networkProjectID, Location: location, Name: "swp-url-lists"}
+	swpSessionMatcher := fmt.Sprintf("inUrlList(host(), '%s')", swpURLListName)
+	opSwpPolicyRules := gc.Runf(t, "network-security gateway-security-policies rules list --gateway-security-policy swp-security-policy --location=%s --project=%s", location, networkProjectID)
+	swpSecurityPolicy := resourcename.GatewaySecurityPolicy{Project: networkProjectID, Location: location, Name: "swp-security-policy"}.String()
+	rep.Check(report.SecureWebProxy, swpSecurityPolicy, func(assert *report.Assertions) {
 		assert.Equal(1, len(opSwpPolicy), "Should have only one Gateway Security Policy")
 
 		// URL lists test
-		swpUrlListValues := []string{
-			"*google.com/go*",
-			"*github.com/GoogleCloudPlatform*",
-			"*github.com/cloudevents*",
-			"*golang.org/x*",
-			"*google.golang.org/*",
-			"*github.com/golang/*",
-			"*github.com/google/*",
-			"*github.com/googleapis/*",
-			"*github.com/json-iterator/go",
-			"*github.com/modern-go/concurrent",
-			"*github.com/modern-go/reflect2",
-			"*go.opencensus.io",
-			"*go.uber.org/atomic",
-			"*go.uber.org/multierr",
-			"*go.uber.org/zap",
+		if !assert.Equal(1, len(opSwpUrlList), "Should have only one URL Lists") {
+			return
 		}
-		opSwpUrlList := gcloud.Runf(t, "network-security url-lists list --location=%s --project=%s", location, networkProjectID).Array()
-		assert.Equal(1, len(opSwpUrlList), "Should have only one URL Lists")
+		assert.Equal(swpURLListName.String(), opSwpUrlList[0].Get("name").String(), fmt.Sprintf("URL List %s should exist", swpURLListName))
 		urlLists := utils.GetResultStrSlice(opSwpUrlList[0].Get("values").Array())
-		assert.Subset(swpUrlListValues, urlLists, fmt.Sprintf("Should have same URL Lists value: %v", swpUrlListValues))
+		assert.Subset(swpURLListValues, urlLists, fmt.Sprintf("Should have same URL Lists value: %v", swpURLListValues))
+		swpURLList, err := swp.NewURLList(urlLists)
+		if !assert.NoError(err) {
+			return
+		}
+		uncovered, err := swp.Uncovered("../../../examples/secure_cloud_function_bigquery_trigger/functions/bq-to-cf", swpURLList, swp.DefaultGOPROXY)
+		assert.NoError(err)
+		assert.Empty(uncovered, "URL Lists should cover every module the Cloud Function build downloads")
 
 		// Gateway Security Policy Rule test
-		swpSessionMatcher := fmt.Sprintf("inUrlList(host(), 'projects/%s/locations/%s/urlLists/swp-url-lists')", networkProjectID, location)
-		opSwpPolicyRule := gcloud.Runf(t, "network-security gateway-security-policies rules list --gateway-security-policy swp-security-policy --location=%s --project=%s", location, networkProjectID).Array()
-		assert.Equal(1, len(opSwpPolicyRule), "Should have only one Gateway Security Policy Rule")
+		opSwpPolicyRule := opSwpPolicyRules.Array()
+		if !assert.Equal(1, len(opSwpPolicyRule), "Should have only one Gateway Security Policy Rule") {
+			return
+		}
 		assert.Equal(swpSessionMatcher, opSwpPolicyRule[0].Get("sessionMatcher").String(), fmt.Sprintf("Should have same session matcher: %s", swpSessionMatcher))
+		swpRules, err := swp.ParseRules([]byte(opSwpPolicyRules.Raw))
+		if assert.NoError(err) {
+			policy, err := swp.NewPolicy(swpRules, map[string]swp.URLList{opSwpUrlList[0].Get("name").String(): swpURLList})
+			if assert.NoError(err) {
+				for host, allowed := range map[string]bool{"cloud.google.com": true, "github.com": true, "example.com": false} {
+					verdict, err := policy.Evaluate(swp.Request{Host: host})
+					assert.NoError(err)
+					assert.Equal(allowed, verdict.Allowed, fmt.Sprintf("Secure Web Proxy should allow %s: %t", host, allowed))
+				}
+			}
+		}
+	})
 
-		// Secure Web Proxy test
-		swpName := fmt.Sprintf("projects/%s/locations/%s/gateways/secure-web-proxy", networkProjectID, location)
-		swpCertificate := fmt.Sprintf("projects/%s/locations/%s/certificates/swp-certificate", networkProjectID, location)
-		swpSecurityPolicy := fmt.Sprintf("projects/%s/locations/%s/gatewaySecurityPolicies/swp-security-policy", networkProjectID, location)
-		swpNetwork := fmt.Sprintf("projects/%s/global/networks/vpc-secure-cloud-function", networkProjectID)
-		swpSubnetwork := fmt.Sprintf("projects/%s/regions/%s/subnetworks/sb-restricted-%s", networkProjectID, location, location)
-		opSwpGateway := gcloud.Runf(t, "network-services gateways describe secure-web-proxy --location=%s --project=%s", location, networkProjectID)
+	// Secure Web Proxy test
+	swpName := resourcename.Gateway{Project: networkProjectID, Location: location, Name: "secure-web-proxy"}.String()
+	swpCertificate := fmt.Sprintf("projects/%s/locations/%s/certificates/swp-certificate", networkProjectID, location)
+	swpNetwork := fmt.Sprintf("projects/%s/global/networks/vpc-secure-cloud-function", networkProjectID)
+	swpSubnetwork := fmt.Sprintf("projects/%s/regions/%s/subnetworks/sb-restricted-%s", networkProjectID, location, location)
+	opSwpGateway := gc.Runf(t, "network-services gateways describe secure-web-proxy --location=%s --project=%s", location, networkProjectID)
+	rep.Check(report.SecureWebProxy, swpName, func(assert *report.Assertions) {
 		assert.Equal(swpName, opSwpGateway.Get("name").String(), fmt.Sprintf("SWP name should be %s", swpName))
 		assert.Equal("SECURE_WEB_GATEWAY", opSwpGateway.Get("type").String(), "SWP type should be SECURE_WEB_GATEWAY")
 		assert.Equal("10.0.0.10", opSwpGateway.Get("addresses").Array()[0].String(), "SWP first address should be 10.0.0.10")
@@ -450,5 +393,20 @@ func TestGCF2BigqueryTrigger(t *testing.T) {
 		assert.Equal(swpSubnetwork, opSwpGateway.Get("subnetwork").String(), fmt.Sprintf("SWP subnetwork should be %s", swpSubnetwork))
 		assert.Equal("samplescope", opSwpGateway.Get("scope").String(), "SWP scope should be samplescope")
 	})
-	bqt.Test()
+
+	// IP address plan test
+	opPSAAddress := gc.Runf(t, "compute addresses describe swp-cloud-function-internal-connection --global --project %s", networkProjectID)
+	ipPlan := ipplan.Plan{
+		SubnetRange:          subnet.Get("ipCidrRange").String(),
+		ProxyRange:           subnetProxy.Get("ipCidrRange").String(),
+		Addresses:            utils.GetResultStrSlice(opSwpGateway.Get("addresses").Array()),
+		RestrictedAPIAddress: allowApiAddress,
+		PSAAddress:           opPSAAddress.Get("address").String(),
+		PSAPrefixLength:      int(opPSAAddress.Get("prefixLength").Int()),
+	}
+	rep.Check(report.AddressPlan, networkName, func(assert *report.Assertions) {
+		for _, c := range ipplan.Validate(ipPlan) {
+			assert.Fail("IP address plan has a conflict.", c.String())
+		}
+	})
 }
@@ -16,71 +16,36 @@ package cloud_function_internal_server
 
 import (
 	"fmt"
-	"strings"
+	"net/netip"
+	"path"
+	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/utils"
 	"github.com/stretchr/testify/assert"
-	"github.com/tidwall/gjson"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/plan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/reachability"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/report"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/resourcename"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
 )
 
-var (
-	RetryableTransientErrors = map[string]string{
-		// Error code 409 for concurrent policy changes.
-		".*Error 409.*There were concurrent policy changes.*": "Concurrent policy changes.",
+const (
+	fixtureDir  = "testdata"
+	planFixture = "testdata/plan.json"
 
-		// API Rate limit exceeded errors can be retried.
-		".*rateLimitExceeded.*": "Rate limit exceeded.",
-
-		// Project deletion is eventually consistent. Even if google_project resources inside the folder are deleted there may be a deletion error.
-		".*FOLDER_TO_DELETE_NON_EMPTY_VIOLATION.*": "Failed to delete non empty folder.",
-
-		// Granting IAM Roles is eventually consistent.
-		".*Error 403.*Permission.*denied on resource.*": "Permission denied on resource.",
-
-		// Editing VPC Service Controls is eventually consistent.
-		".*Error 403.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*":    "Request is prohibited by organization's policy.",
-		".*Error code 7.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*": "Request is prohibited by organization's policy.",
-
-		// Google Storage Service Agent propagation issue.
-		".*Error 400.*Service account service-.*@gs-project-accounts.iam.gserviceaccount.com does not exist.*": "Google Storage Service Agent propagation issue",
-	}
+	// Keys of the function and of the mutual TLS secrets in the plan.
+	functionKey = "module.secure_cloud_function.module.cloud_function_security.module.cloud_function_kms.google_kms_crypto_key.key_ephemeral[0]"
+	mtlsKey     = "module.mtls_kms.google_kms_crypto_key.key_ephemeral[0]"
 )
 
-type Protocols struct {
-	Protocol string
-	Ports    []string
-}
-
-func GetLastSplitElement(value string, sep string) string {
-	splitted := strings.Split(value, sep)
-	return splitted[len(splitted)-1]
-}
-
-func GetResultFieldStrSlice(rs []gjson.Result, field string) []string {
-	s := make([]string, 0)
-	for _, r := range rs {
-		s = append(s, r.Get(field).String())
-	}
-	return s
-}
-
-// GetOrgACMPolicyID gets the Organization Access Context Manager Policy ID
-func GetOrgACMPolicyID(t testing.TB, orgID string) string {
-	filter := fmt.Sprintf("parent:organizations/%s", orgID)
-	id := gcloud.Runf(t, "access-context-manager policies list --organization %s --filter %s --quiet", orgID, filter).Array()
-	if len(id) == 0 {
-		return ""
-	}
-	return GetLastSplitElement(id[0].Get("name").String(), "/")
-}
-
 func TestCFInternalServer(t *testing.T) {
-	orgID := utils.ValFromEnv(t, "TF_VAR_org_id")
-	policyID := GetOrgACMPolicyID(t, orgID)
+	gc := testutils.NewGCloud(t, testutils.FixturePath(fixtureDir))
+	orgID := gc.ValFromEnv(t, "TF_VAR_org_id")
+	policyID := gc.GetOrgACMPolicyID(t, orgID)
 	createACM := false
 
 	vars := map[string]interface{}{
@@ -97,50 +62,176 @@ func TestCFInternalServer(t *testing.T) {
 
 	cft := tft.NewTFBlueprintTest(t,
 		tft.WithVars(vars),
-		tft.WithRetryableTerraformErrors(RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithRetryableTerraformErrors(testutils.RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithLogger(testutils.NewTransientErrorClassifier().Logger(t, utils.GetLoggerFromT())),
 	)
 
 	cft.DefineVerify(func(assert *assert.Assertions) {
-		// Removing DefaultVerify because Cloud Function API is changing the build_config/source/storage_source/generation and this modification is breaking the build validation.
-		// cft.DefaultVerify(assert)
-
-		location := "us-west1"
-		networkProjectID := cft.GetStringOutput("network_project_id")
-		projectID := cft.GetStringOutput("serverless_project_id")
-		functionName := cft.GetStringOutput("cloud_function_name")
-		connectorID := cft.GetStringOutput("connector_id")
-		saEmail := cft.GetStringOutput("service_account_email")
-
-		cf := gcloud.Runf(t, "functions describe %s --project %s --gen2 --region %s", functionName, projectID, location)
-		cfTrigger := cf.Get("eventTrigger.trigger")
-		assert.Equal("ACTIVE", cf.Get("state").String(), "Should be ACTIVE. Cloud Function is not successfully deployed.")
-		assert.Equal(connectorID, cf.Get("serviceConfig.vpcConnector").String(), fmt.Sprintf("VPC Connector should be %s. Connector was not set.", connectorID))
-		assert.Equal("ALL_TRAFFIC", cf.Get("serviceConfig.vpcConnectorEgressSettings").String(), "Egress setting should be ALL_TRAFFIC.")
-		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.Get("serviceConfig.ingressSettings").String(), "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
-		assert.Equal(saEmail, cf.Get("serviceConfig.serviceAccountEmail").String(), fmt.Sprintf("Cloud Function should use the service account %s.", saEmail))
-		assert.Equal("google.cloud.storage.object.v1.finalized", cf.Get("eventTrigger.eventType").String(), "Cloud Function EventType should be google.cloud.storage.object.v1.finalized.")
-		assert.NotNil(t, cfTrigger, "Trigger should exist.")
-
-		gcloudArgsBucket := gcloud.WithCommonArgs([]string{"--project", projectID, "--json"})
-		bucketName := cft.GetStringOutput("cloudfunction_bucket_name")
-		opBucket := gcloud.Run(t, fmt.Sprintf("alpha storage ls --buckets gs://%s", bucketName), gcloudArgsBucket).Array()
+		plan.DefaultVerify(assert, cft, plan.VolatileAttributes)
+
+		verify(t, gc, gc.Outputs(t, cft.GetStringOutput))
+	})
+	cft.Test()
+}
+
+// TestCFInternalServerReplay runs the verify stage against the gcloud fixture recorded in each location.
+func TestCFInternalServerReplay(t *testing.T) {
+	for _, fixture := range testutils.Fixtures(t, fixtureDir) {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			gc := testutils.NewReplayGCloud(t, fixture)
+			verify(t, gc, gc.Outputs(t, nil))
+		})
+	}
+}
+
+// TestCFInternalServerPlan checks the planned resources of the example without deploying it.
+func TestCFInternalServerPlan(t *testing.T) {
+	p, err := plan.Load(planFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	// The Cloud Services agent needs roles/editor on the network project to manage the internal server network.
+	assert.Equal([]plan.Finding{{
+		Check:   plan.CheckIAMPrimitiveRole,
+		Address: "google_project_iam_member.network_service_agent_editor",
+		Message: "grants primitive role roles/editor",
+	}}, plan.Verify(p))
+
+	// The connector, worker pool, keys and secrets are created by the same plan,
+	// so the function and the secrets only refer to them with unknown values.
+	functions := p.Resources("google_cloudfunctions2_function")
+	if assert.Len(functions, 1) {
+		fn := functions[0]
+		assert.Equal("ALLOW_INTERNAL_AND_GCLB", fn.Values.Get("service_config.0.ingress_settings").String(), "function should only allow internal ingress")
+		assert.Equal("ALL_TRAFFIC", fn.Values.Get("service_config.0.vpc_connector_egress_settings").String(), "function should send all egress through the connector")
+		assert.True(fn.Unknown.Get("service_config.0.vpc_connector").Bool(), "function should use the connector of the example")
+		assert.True(fn.Unknown.Get("build_config.0.worker_pool").Bool(), "function should be built in the private worker pool of the example")
+		assert.True(fn.Unknown.Get("kms_key_name").Bool(), "function should be encrypted with the key of the example")
+		assert.Equal("https", fn.Values.Get("service_config.0.environment_variables.TARGET_SCHEME").String(), "function should call the internal server over HTTPS")
+		assert.Len(fn.Values.Get("service_config.0.secret_volumes").Array(), 3, "function should mount the client certificate, key and CA bundle")
+		for _, v := range fn.Values.Get("service_config.0.secret_volumes").Array() {
+			assert.Equal("latest", v.Get("versions.0.version").String(), "secret volume %s should follow the latest version", v.Get("mount_path").String())
+		}
+	}
+	secrets := p.Resources("google_secret_manager_secret")
+	if assert.Len(secrets, 4) {
+		for _, s := range secrets {
+			assert.True(s.Unknown.Get("replication.0.user_managed.0.replicas.0.customer_managed_encryption.0.kms_key_name").Bool(), "secret %s should be encrypted with a customer managed key", s.Address)
+		}
+	}
+	assert.Len(p.Resources("google_secret_manager_secret_version"), 4, "the client certificate, key, CA bundle and server key should be stored")
+	server, ok := p.Resource("google_compute_instance.internal_server")
+	member, memberOK := p.Resource("google_secret_manager_secret_iam_member.mtls_server_key")
+	if assert.True(ok, "internal server should be planned") && assert.True(memberOK, "server key accessor should be planned") {
+		assert.False(server.Values.Get("metadata.mtls-server-key").Exists() || server.Unknown.Get("metadata.mtls-server-key").Exists(), "internal server key should not be in the instance metadata")
+		assert.True(server.Unknown.Get("metadata.mtls-server-key-secret").Bool(), "internal server should read its key from Secret Manager")
+		assert.Equal("serviceAccount:"+server.Values.Get("service_account.0.email").String(), member.Values.Get("member").String(), "only the internal server should read its key")
+	}
+	for _, address := range []string{functionKey, mtlsKey} {
+		if key, ok := p.Resource(address); assert.True(ok, "key %s should be planned", address) {
+			assert.Equal("HSM", key.Values.Get("version_template.0.protection_level").String(), "protection level of key %s", address)
+		}
+	}
+	firewalls := p.Resources("google_compute_firewall")
+	if assert.Len(firewalls, 1) {
+		assert.Equal([]string{"8000"}, utils.GetResultStrSlice(firewalls[0].Values.Get("allow.0.ports").Array()), "internal server firewall ports")
+	}
+}
+
+func verify(t testing.TB, gc *testutils.GCloud, output func(string) string) {
+	location := output("location")
+	zone := output("zone")
+	networkProjectID := output("network_project_id")
+	projectID := output("serverless_project_id")
+	functionName := output("cloud_function_name")
+	connectorID := output("connector_id")
+	saEmail := output("service_account_email")
+	cfKMSKey := resourcename.CryptoKey{Project: output("security_project_id"), Location: location, KeyRing: "krg-secure-cloud-function", Name: "key-secure-cloud-function"}.String()
+
+	rep := report.New(t)
+	cf := gc.DescribeCloudFunction(t, functionName, projectID, location)
+	repo, err := audit.ParseArtifactRepository([]byte(gc.Runf(t, "artifacts repositories describe %s", cf.BuildConfig.DockerRepository).Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := audit.Baseline{VPCConnector: connectorID, ServiceAccountEmail: saEmail, Repository: &repo, KMSKey: cfKMSKey}
+	violations := audit.Audit(cf, baseline)
+	rep.Check(report.FunctionActive, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ACTIVE", cf.State, "Should be ACTIVE. Cloud Function is not successfully deployed.")
+	})
+	rep.Check(report.FunctionIngress, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.ServiceConfig.IngressSettings, "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
+		report.AssertNoViolations(assert, violations, audit.RuleInternalIngress)
+	})
+	rep.Check(report.FunctionEgress, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleVPCConnector, audit.RuleEgressAllTraffic)
+	})
+	rep.Check(report.PrivateWorkerPool, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RulePrivateWorkerPool)
+	})
+	rep.Check(report.FunctionCMEK, cf.Name, func(assert *report.Assertions) {
+		report.AssertCMEK(assert, baseline, violations)
+	})
+	rep.Check(report.FunctionIdentity, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleDedicatedSA)
+	})
+	rep.Check(report.FunctionConfiguration, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("https", cf.ServiceConfig.EnvironmentVariables["TARGET_SCHEME"], "Should call the internal server over HTTPS.")
+		for _, key := range []string{"TLS_CLIENT_CERT_FILE", "TLS_CLIENT_KEY_FILE", "TLS_CA_FILE"} {
+			file := cf.ServiceConfig.EnvironmentVariables[key]
+			volume, ok := cf.ServiceConfig.SecretVolume(path.Dir(file))
+			if assert.True(ok, "%s %q should be in a secret volume.", key, file) && assert.Len(volume.Versions, 1) {
+				assert.Equal(path.Base(file), volume.Versions[0].Path, "%s should be the file of the secret volume.", key)
+				assert.Equal("latest", volume.Versions[0].Version, "Secret volume %s should follow the latest version.", volume.MountPath)
+			}
+		}
+	})
+	rep.Check(report.FunctionTrigger, cf.Name, func(assert *report.Assertions) {
+		if assert.NotNil(cf.EventTrigger, "Trigger should exist.") {
+			assert.Equal("google.cloud.storage.object.v1.finalized", cf.EventTrigger.EventType, "Cloud Function EventType should be google.cloud.storage.object.v1.finalized.")
+			assert.NotEmpty(cf.EventTrigger.Trigger, "Trigger should exist.")
+		}
+	})
+
+	gcloudArgsBucket := []string{"--project", projectID, "--json"}
+	bucketName := output("cloudfunction_bucket_name")
+	opBucket := gc.Run(t, fmt.Sprintf("alpha storage ls --buckets gs://%s", bucketName), gcloudArgsBucket).Array()
+	rep.Check(report.Resources, bucketName, func(assert *report.Assertions) {
 		assert.Equal(bucketName, opBucket[0].Get("metadata.name").String(), fmt.Sprintf("The bucket name should be %s.", bucketName))
 		assert.True(opBucket[0].Exists(), "Bucket %s should exist.", bucketName)
+	})
 
-		instanceName := "webserver"
-		instanceZone := fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/us-west1-b", projectID)
-		opInstance := gcloud.Runf(t, "compute instances describe %s --zone=us-west1-b --project=%s", instanceName, projectID)
+	instanceName := "webserver"
+	instanceZone := fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/%s", projectID, zone)
+	opInstance := gc.Runf(t, "compute instances describe %s --zone=%s --project=%s", instanceName, zone, projectID)
+	rep.Check(report.Resources, instanceName, func(assert *report.Assertions) {
 		assert.Equal(instanceName, opInstance.Get("name").String(), fmt.Sprintf("Instance name should be %s", instanceName))
 		assert.Equal(instanceZone, opInstance.Get("zone").String(), fmt.Sprintf("Instance should be in zone %s", instanceZone))
+	})
 
-		denyAllEgressName := "fw-e-shared-restricted-internal-server"
-		denyAllEgressRule := gcloud.Runf(t, "compute firewall-rules describe %s --project %s", denyAllEgressName, networkProjectID)
-		assert.Equal(denyAllEgressName, denyAllEgressRule.Get("name").String(), fmt.Sprintf("firewall rule %s should exist", denyAllEgressName))
-		assert.Equal("EGRESS", denyAllEgressRule.Get("direction").String(), fmt.Sprintf("firewall rule %s direction should be EGRESS", denyAllEgressName))
-		assert.True(denyAllEgressRule.Get("logConfig.enable").Bool(), fmt.Sprintf("firewall rule %s should have log configuration enabled", denyAllEgressName))
-		assert.Equal("10.0.0.0/28", denyAllEgressRule.Get("destinationRanges").Array()[0].String(), fmt.Sprintf("firewall rule %s destination ranges should be 10.0.0.0/28", denyAllEgressName))
-		assert.Equal("8000", denyAllEgressRule.Get("allowed.0.ports.0").String(), fmt.Sprintf("firewall rule %s should allow port 8000", denyAllEgressName))
+	rep.Check(report.FirewallRules, networkProjectID, func(assert *report.Assertions) {
+		gc.AssertFirewallRules(t, assert, networkProjectID, testutils.FirewallExpectation{
+			Name:              "fw-e-shared-restricted-internal-server",
+			Direction:         "EGRESS",
+			LogEnabled:        true,
+			DestinationRanges: []string{"10.0.0.0/28"},
+			Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"8000"}}},
+		})
+	})
 
+	// Egress reachability test
+	rep.Check(report.EgressReachability, connectorID, func(assert *report.Assertions) {
+		connectorName, err := resourcename.ParseConnector(connectorID)
+		if !assert.NoError(err) {
+			return
+		}
+		sim, err := reachability.New(gc.ListFirewallRules(t, networkProjectID, output("service_vpc_name")))
+		if assert.NoError(err) {
+			connector := reachability.ConnectorInstance(connectorName.Location, connectorName.Name)
+			instanceIP := netip.MustParseAddr(opInstance.Get("networkInterfaces.0.networkIP").String())
+			assert.True(sim.Egress(connector, instanceIP, "tcp", 8000).Allowed, fmt.Sprintf("connector should reach %s on port 8000", instanceIP))
+			assert.False(sim.Egress(connector, netip.MustParseAddr("8.8.8.8"), "tcp", 443).Allowed, "connector should not reach the internet")
+		}
 	})
-	cft.Test()
 }
@@ -16,60 +16,29 @@ package secure_cloud_function_with_sql
 
 import (
 	"fmt"
-	"strings"
+	"net/netip"
+	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/tft"
 	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/audit"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/cmek"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/plan"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/reachability"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/report"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/resourcename"
+	"github.com/terraform-google-modules/cloud-functions/test/integration/testutils"
 )
 
-var (
-	RetryableTransientErrors = map[string]string{
-		// Error code 409 for concurrent policy changes.
-		".*Error 409.*There were concurrent policy changes.*": "Concurrent policy changes.",
-
-		// API Rate limit exceeded errors can be retried.
-		".*rateLimitExceeded.*": "Rate limit exceeded.",
-
-		// Project deletion is eventually consistent. Even if google_project resources inside the folder are deleted there may be a deletion error.
-		".*FOLDER_TO_DELETE_NON_EMPTY_VIOLATION.*": "Failed to delete non empty folder.",
-
-		// Granting IAM Roles is eventually consistent.
-		".*Error 403.*Permission.*denied on resource.*": "Permission denied on resource.",
-
-		// Error 403: Compute Engine API has not been used in project {} before or it is disabled.
-		".*Error 403.*Compute Engine API has not been used in project.*": "Compute Engine API not enabled",
-
-		// Editing VPC Service Controls is eventually consistent.
-		".*Error 403.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*":    "Request is prohibited by organization's policy.",
-		".*Error code 7.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*": "Request is prohibited by organization's policy.",
-
-		// Google Storage Service Agent propagation issue.
-		".*Error 400.*Service account service-.*@gs-project-accounts.iam.gserviceaccount.com does not exist.*": "Google Storage Service Agent propagation issue",
-	}
-)
-
-// GetOrgACMPolicyID gets the Organization Access Context Manager Policy ID
-func GetOrgACMPolicyID(t testing.TB, orgID string) string {
-	filter := fmt.Sprintf("parent:organizations/%s", orgID)
-	id := gcloud.Runf(t, "access-context-manager policies list --organization %s --filter %s --quiet", orgID, filter).Array()
-	if len(id) == 0 {
-		return ""
-	}
-	return GetLastSplitElement(id[0].Get("name").String(), "/")
-}
-
-func GetLastSplitElement(value string, sep string) string {
-	splitted := strings.Split(value, sep)
-	return splitted[len(splitted)-1]
-}
+const fixtureDir = "testdata"
 
 func TestGCF2CloudSQL(t *testing.T) {
-	orgID := utils.ValFromEnv(t, "TF_VAR_org_id")
-	policyID := GetOrgACMPolicyID(t, orgID)
+	gc := testutils.NewGCloud(t, testutils.FixturePath(fixtureDir))
+	orgID := gc.ValFromEnv(t, "TF_VAR_org_id")
+	policyID := gc.GetOrgACMPolicyID(t, orgID)
 	createACM := false
 
 	vars := map[string]interface{}{
@@ -86,77 +55,175 @@ func TestGCF2CloudSQL(t *testing.T) {
 
 	cf2SQL := tft.NewTFBlueprintTest(t,
 		tft.WithVars(vars),
-		tft.WithRetryableTerraformErrors(RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithRetryableTerraformErrors(testutils.RetryableTransientErrors, 5, 1*time.Minute),
+		tft.WithLogger(testutils.NewTransientErrorClassifier().Logger(t, utils.GetLoggerFromT())),
 	)
 
 	cf2SQL.DefineVerify(func(assert *assert.Assertions) {
-		// Removing DefaultVerify because Cloud Function API is changing the build_config/source/storage_source/generation and this modification is breaking the build validation.
-		// cf2SQL.DefaultVerify(assert)
-
-		name := cf2SQL.GetStringOutput("cloud_function_name")
-		location := "us-central1"
-		connectorID := cf2SQL.GetStringOutput("connector_id")
-		saEmail := cf2SQL.GetStringOutput("service_account_email")
-		mysqlName := cf2SQL.GetStringOutput("mysql_name")
-		mysqlUser := cf2SQL.GetStringOutput("mysql_user")
-		mySQLPrivIP := cf2SQL.GetStringOutput("mysql_private_ip_address")
-		projectID := cf2SQL.GetStringOutput("serverless_project_id")
-		netProjectID := cf2SQL.GetStringOutput("network_project_id")
-		sqlProjectID := cf2SQL.GetStringOutput("cloudsql_project_id")
-		secProjectID := cf2SQL.GetStringOutput("security_project_id")
-		topicID := cf2SQL.GetStringOutput("topic_id")
-		topicKMS := cf2SQL.GetStringOutput("topic_kms_key")
-		sqlKMS := cf2SQL.GetStringOutput("cloud_sql_kms_key")
-		scrName := cf2SQL.GetStringOutput("secret_manager_name")
-		schName := cf2SQL.GetStringOutput("scheduler_name")
-		secretName := cf2SQL.GetStringOutput("secret_manager_name")
-		secretVersion := cf2SQL.GetStringOutput("secret_manager_version")
-		secretKMS := cf2SQL.GetStringOutput("secret_kms_key")
-		sctVersionFull := fmt.Sprintf("%s/cryptoKeyVersions/%s", secretKMS, secretVersion)
-
-		cf := gcloud.Runf(t, "functions describe %s --project %s --gen2 --region %s", name, projectID, location)
-		assert.Equal("ACTIVE", cf.Get("state").String(), "Should be ACTIVE. Cloud Function is not successfully deployed.")
-		assert.Equal(connectorID, cf.Get("serviceConfig.vpcConnector").String(), fmt.Sprintf("VPC Connector should be %s. Connector was not set.", connectorID))
-		assert.Equal("ALL_TRAFFIC", cf.Get("serviceConfig.vpcConnectorEgressSettings").String(), "Egress setting should be ALL_TRAFFIC.")
-		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.Get("serviceConfig.ingressSettings").String(), "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
-		assert.Equal(saEmail, cf.Get("serviceConfig.serviceAccountEmail").String(), fmt.Sprintf("Cloud Function should use the service account %s.", saEmail))
-		assert.Equal("google.cloud.pubsub.topic.v1.messagePublished", cf.Get("eventTrigger.eventType").String(), "Event Trigger is not a message published on topic.")
-		assert.Equal(topicID, cf.Get("eventTrigger.pubsubTopic").String(), fmt.Sprintf("Event Trigger topic is not %s.", topicID))
-		assert.Equal("INSTANCE_PWD", cf.Get("serviceConfig.secretEnvironmentVariables.0.key").String(), "Should have secret environment key INSTANCE_PWD")
-		assert.Equal(scrName, cf.Get("serviceConfig.secretEnvironmentVariables.0.secret").String(), fmt.Sprintf("Should have secret environment key %s", scrName))
-		assert.Equal("db-application", cf.Get("serviceConfig.environmentVariables.DATABASE_NAME").String(), "SShould have env var DATABASE_NAME with value db-application")
-		assert.Equal(location, cf.Get("serviceConfig.environmentVariables.INSTANCE_LOCATION").String(), fmt.Sprintf("Should have env var INSTANCE_LOCATION with value %s", location))
-		assert.Equal(mysqlName, cf.Get("serviceConfig.environmentVariables.INSTANCE_NAME").String(), fmt.Sprintf("Should have env var INSTANCE_NAME with value %s", mysqlName))
-		assert.Equal(mysqlUser, cf.Get("serviceConfig.environmentVariables.INSTANCE_USER").String(), fmt.Sprintf("Should have environment var INSTANCE_USER with value %s", mysqlUser))
-		assert.Equal(sqlProjectID, cf.Get("serviceConfig.environmentVariables.INSTANCE_PROJECT_ID").String(), fmt.Sprintf("Should have environment var with value %s", sqlProjectID))
-
-		cf = gcloud.Runf(t, "sql instances describe %s --project %s", mysqlName, sqlProjectID)
-		assert.Equal("RUNNABLE", cf.Get("state").String(), "Should be RUNNABLE. Cloud SQL is not successfully deployed.")
-		assert.Equal("PRIVATE", cf.Get("ipAddresses.0.type").String(), "Should be PRIVATE. Cloud SQL should have only PRIVATE IPs.")
-		assert.Equal(sqlKMS, cf.Get("diskEncryptionConfiguration.kmsKeyName").String(), fmt.Sprintf("Cloud SQL should be encrypting disk with %s", sqlKMS))
-
-		cf = gcloud.Runf(t, "pubsub topics describe %s", topicID)
-		assert.Equal(topicKMS, cf.Get("kmsKeyName").String(), fmt.Sprintf("Pub/Sub topic should be encrypting messages with %s", topicKMS))
-
-		cf = gcloud.Runf(t, "scheduler jobs describe %s --project %s --location %s", schName, projectID, location)
-		assert.Equal(topicID, cf.Get("pubsubTarget.topicName").String(), fmt.Sprintf("Scheduler should publish messages in topic %s", topicID))
-
-		cf = gcloud.Runf(t, "secrets describe %s --project %s", secretName, secProjectID)
-		assert.Equal(secretKMS, cf.Get("replication.userManaged.replicas.0.customerManagedEncryption.kmsKeyName").String(), fmt.Sprintf("Secret should have KMS key configured %s", secretKMS))
-		cf = gcloud.Runf(t, "secrets versions describe %s --secret  %s --project %s", secretVersion, secretName, secProjectID)
-		assert.Equal(sctVersionFull, cf.Get("replicationStatus.userManaged.replicas.0.customerManagedEncryption.kmsKeyVersionName").String(), fmt.Sprintf("Secret should have KMS key configured %s", secretKMS))
-
-		allowTCP3307 := "fw-allow-tcp-3307-egress-to-sql-private-ip"
-		allowTCP3307Rule := gcloud.Runf(t, "compute firewall-rules describe %s --project %s", allowTCP3307, netProjectID)
-		assert.Equal(allowTCP3307, allowTCP3307Rule.Get("name").String(), fmt.Sprintf("firewall rule %s should exist", allowTCP3307))
-		assert.Equal("EGRESS", allowTCP3307Rule.Get("direction").String(), fmt.Sprintf("firewall rule %s direction should be EGRESS", allowTCP3307))
-		assert.True(allowTCP3307Rule.Get("logConfig.enable").Bool(), fmt.Sprintf("firewall rule %s should have log configuration enabled", allowTCP3307))
-		assert.Equal(mySQLPrivIP, allowTCP3307Rule.Get("destinationRanges").Array()[0].String(), fmt.Sprintf("firewall rule %s destination ranges should be %s", allowTCP3307, mySQLPrivIP))
-		assert.Equal(1, len(allowTCP3307Rule.Get("allowed").Array()), fmt.Sprintf("firewall rule %s should have only one allowed", allowTCP3307))
-		assert.Equal(1, len(allowTCP3307Rule.Get("allowed.0.ports").Array()), fmt.Sprintf("firewall rule %s should allow only one protocol and one port", allowTCP3307))
-		assert.Equal("tcp", allowTCP3307Rule.Get("allowed.0.IPProtocol").String(), fmt.Sprintf("firewall rule %s should allow only TCP protocols", allowTCP3307))
-		assert.Equal("3307", allowTCP3307Rule.Get("allowed.0.ports.0").String(), fmt.Sprintf("firewall rule %s should allow only port 3307", allowTCP3307))
+		plan.DefaultVerify(assert, cf2SQL, plan.VolatileAttributes)
 
+		verify(t, gc, gc.Outputs(t, cf2SQL.GetStringOutput))
 	})
 	cf2SQL.Test()
 }
+
+// TestGCF2CloudSQLReplay runs the verify stage against the gcloud fixture recorded in each location.
+func TestGCF2CloudSQLReplay(t *testing.T) {
+	for _, fixture := range testutils.Fixtures(t, fixtureDir) {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			gc := testutils.NewReplayGCloud(t, fixture)
+			verify(t, gc, gc.Outputs(t, nil))
+		})
+	}
+}
+
+func verify(t testing.TB, gc *testutils.GCloud, output func(string) string) {
+	name := output("cloud_function_name")
+	location := output("location")
+	connectorID := output("connector_id")
+	saEmail := output("service_account_email")
+	mysqlName := output("mysql_name")
+	mysqlUser := output("mysql_user")
+	mySQLPrivIP := output("mysql_private_ip_address")
+	projectID := output("serverless_project_id")
+	netProjectID := output("network_project_id")
+	sqlProjectID := output("cloudsql_project_id")
+	secProjectID := output("security_project_id")
+	topicID := output("topic_id")
+	topicKMS := output("topic_kms_key")
+	sqlKMS := output("cloud_sql_kms_key")
+	scrName := output("secret_manager_name")
+	schName := output("scheduler_name")
+	secretName := output("secret_manager_name")
+	secretVersion := output("secret_manager_version")
+	secretKMS := output("secret_kms_key")
+	cfKMSKey := resourcename.CryptoKey{Project: secProjectID, Location: location, KeyRing: "krg-secure-cloud-function", Name: "key-secure-cloud-function"}.String()
+
+	rep := report.New(t)
+	cf := gc.DescribeCloudFunction(t, name, projectID, location)
+	repo, err := audit.ParseArtifactRepository([]byte(gc.Runf(t, "artifacts repositories describe %s", cf.BuildConfig.DockerRepository).Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := audit.Baseline{VPCConnector: connectorID, ServiceAccountEmail: saEmail, Repository: &repo, KMSKey: cfKMSKey}
+	violations := audit.Audit(cf, baseline)
+	rep.Check(report.FunctionActive, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ACTIVE", cf.State, "Should be ACTIVE. Cloud Function is not successfully deployed.")
+	})
+	rep.Check(report.FunctionIngress, cf.Name, func(assert *report.Assertions) {
+		assert.Equal("ALLOW_INTERNAL_AND_GCLB", cf.ServiceConfig.IngressSettings, "Ingress setting should be ALLOW_INTERNAL_AND_GCLB.")
+		report.AssertNoViolations(assert, violations, audit.RuleInternalIngress)
+	})
+	rep.Check(report.FunctionEgress, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleVPCConnector, audit.RuleEgressAllTraffic)
+	})
+	rep.Check(report.PrivateWorkerPool, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RulePrivateWorkerPool)
+	})
+	rep.Check(report.FunctionCMEK, cf.Name, func(assert *report.Assertions) {
+		report.AssertCMEK(assert, baseline, violations)
+	})
+	rep.Check(report.FunctionIdentity, cf.Name, func(assert *report.Assertions) {
+		report.AssertNoViolations(assert, violations, audit.RuleDedicatedSA)
+	})
+	rep.Check(report.FunctionTrigger, cf.Name, func(assert *report.Assertions) {
+		if assert.NotNil(cf.EventTrigger, "Event Trigger should exist.") {
+			assert.Equal("google.cloud.pubsub.topic.v1.messagePublished", cf.EventTrigger.EventType, "Event Trigger is not a message published on topic.")
+			assert.Equal(topicID, cf.EventTrigger.PubsubTopic, fmt.Sprintf("Event Trigger topic is not %s.", topicID))
+		}
+	})
+	rep.Check(report.FunctionConfiguration, cf.Name, func(assert *report.Assertions) {
+		secretEnv, ok := cf.ServiceConfig.SecretEnvironmentVariable("INSTANCE_PWD")
+		assert.True(ok, "Should have secret environment key INSTANCE_PWD")
+		assert.Equal(scrName, secretEnv.Secret, fmt.Sprintf("Should have secret environment key %s", scrName))
+		assert.Equal("db-application", cf.ServiceConfig.EnvironmentVariables["DATABASE_NAME"], "SShould have env var DATABASE_NAME with value db-application")
+		assert.Equal(location, cf.ServiceConfig.EnvironmentVariables["INSTANCE_LOCATION"], fmt.Sprintf("Should have env var INSTANCE_LOCATION with value %s", location))
+		assert.Equal(mysqlName, cf.ServiceConfig.EnvironmentVariables["INSTANCE_NAME"], fmt.Sprintf("Should have env var INSTANCE_NAME with value %s", mysqlName))
+		assert.Equal(mysqlUser, cf.ServiceConfig.EnvironmentVariables["INSTANCE_USER"], fmt.Sprintf("Should have environment var INSTANCE_USER with value %s", mysqlUser))
+		assert.Equal(sqlProjectID, cf.ServiceConfig.EnvironmentVariables["INSTANCE_PROJECT_ID"], fmt.Sprintf("Should have environment var with value %s", sqlProjectID))
+	})
+
+	op := gc.Runf(t, "sql instances describe %s --project %s", mysqlName, sqlProjectID)
+	sqlInstance := op.Get("name").String()
+	cmekUsages := cmek.Collect(sqlInstance, "Cloud SQL instance", op.Get("region").String(), op, cmek.ServiceAgent(cmek.ServiceCloudSQL, gc.GetProjectNumber(t, sqlProjectID)))
+	rep.Check(report.Resources, sqlInstance, func(assert *report.Assertions) {
+		assert.Equal("RUNNABLE", op.Get("state").String(), "Should be RUNNABLE. Cloud SQL is not successfully deployed.")
+	})
+	rep.Check(report.PrivateDatabase, sqlInstance, func(assert *report.Assertions) {
+		assert.Equal("PRIVATE", op.Get("ipAddresses.0.type").String(), "Should be PRIVATE. Cloud SQL should have only PRIVATE IPs.")
+	})
+	rep.Check(report.DataCMEK, sqlInstance, func(assert *report.Assertions) {
+		assert.Equal(sqlKMS, op.Get("diskEncryptionConfiguration.kmsKeyName").String(), fmt.Sprintf("Cloud SQL should be encrypting disk with %s", sqlKMS))
+	})
+
+	op = gc.Runf(t, "pubsub topics describe %s", topicID)
+	cmekUsages = append(cmekUsages, cmek.Collect(topicID, "Pub/Sub topic", "", op, cmek.ServiceAgent(cmek.ServicePubSub, gc.GetProjectNumber(t, projectID)))...)
+	rep.Check(report.DataCMEK, topicID, func(assert *report.Assertions) {
+		assert.Equal(topicKMS, op.Get("kmsKeyName").String(), fmt.Sprintf("Pub/Sub topic should be encrypting messages with %s", topicKMS))
+	})
+
+	op = gc.Runf(t, "scheduler jobs describe %s --project %s --location %s", schName, projectID, location)
+	rep.Check(report.Resources, op.Get("name").String(), func(assert *report.Assertions) {
+		assert.Equal(topicID, op.Get("pubsubTarget.topicName").String(), fmt.Sprintf("Scheduler should publish messages in topic %s", topicID))
+	})
+
+	op = gc.Runf(t, "secrets describe %s --project %s", secretName, secProjectID)
+	secret := op.Get("name").String()
+	cmekUsages = append(cmekUsages, cmek.Collect(secret, "Secret Manager secret", op.Get("replication.userManaged.replicas.0.location").String(), op, cmek.ServiceAgent(cmek.ServiceSecretManager, gc.GetProjectNumber(t, secProjectID)))...)
+	rep.Check(report.DataCMEK, secret, func(assert *report.Assertions) {
+		secretID, err := resourcename.ParseSecret(secret)
+		if assert.NoError(err) {
+			assert.Equal(secretName, secretID.Name, fmt.Sprintf("Secret should be named %s", secretName))
+		}
+		secretKey, err := resourcename.ParseCryptoKey(secretKMS)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(secretKMS, op.Get("replication.userManaged.replicas.0.customerManagedEncryption.kmsKeyName").String(), fmt.Sprintf("Secret should have KMS key configured %s", secretKMS))
+		op = gc.Runf(t, "secrets versions describe %s --secret  %s --project %s", secretVersion, secretName, secProjectID)
+		assert.Equal(secretKey.Version(secretVersion).String(), op.Get("replicationStatus.userManaged.replicas.0.customerManagedEncryption.kmsKeyVersionName").String(), fmt.Sprintf("Secret should have KMS key configured %s", secretKMS))
+	})
+
+	// CMEK coverage test
+	describeKey := func(key string) (cmek.Key, error) {
+		return cmek.ParseKey([]byte(gc.Runf(t, "kms keys describe %s", key).Raw), []byte(gc.Runf(t, "kms keys get-iam-policy %s", key).Raw))
+	}
+	rep.Check(report.CMEKCoverage, projectID, func(assert *report.Assertions) {
+		kmsExpectations, err := cmek.ExpectationsFromTerraform("../../../examples/secure_cloud_function_with_sql/main.tf")
+		if !assert.NoError(err) {
+			return
+		}
+		table, err := cmek.Verify(cmekUsages, describeKey, kmsExpectations)
+		if !assert.NoError(err) {
+			return
+		}
+		t.Logf("CMEK coverage:\n%s", table)
+		for _, f := range table.Findings() {
+			assert.Fail("CMEK key is not consistent with the resource it encrypts.", f.String())
+		}
+	})
+
+	rep.Check(report.FirewallRules, netProjectID, func(assert *report.Assertions) {
+		gc.AssertFirewallRules(t, assert, netProjectID, testutils.FirewallExpectation{
+			Name:              "fw-allow-tcp-3307-egress-to-sql-private-ip",
+			Direction:         "EGRESS",
+			LogEnabled:        true,
+			DestinationRanges: []string{mySQLPrivIP},
+			Allowed:           []testutils.Protocols{{Protocol: "tcp", Ports: []string{"3307"}}},
+		})
+	})
+
+	// Egress reachability test
+	rep.Check(report.EgressReachability, connectorID, func(assert *report.Assertions) {
+		connectorName, err := resourcename.ParseConnector(connectorID)
+		if !assert.NoError(err) {
+			return
+		}
+		sim, err := reachability.New(gc.ListFirewallRules(t, netProjectID, output("service_vpc_name")))
+		if assert.NoError(err) {
+			connector := reachability.ConnectorInstance(connectorName.Location, connectorName.Name)
+			sqlPorts := sim.AllowedPorts(connector, netip.MustParseAddr(mySQLPrivIP), "tcp")
+			assert.Equal([]reachability.PortRange{{From: 3307, To: 3307}}, sqlPorts, fmt.Sprintf("connector should reach %s only on port 3307", mySQLPrivIP))
+		}
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Modules returns the paths of the modules the Cloud Build fetch of the Go
+// function in dir downloads: the requirements of its go.mod and, when present,
+// every module recorded in its go.sum. Modules replaced by a local directory are skipped.
+func Modules(dir string) ([]string, error) {
+	gomod := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", gomod, err)
+	}
+
+	local := map[string]bool{}
+	for _, r := range f.Replace {
+		if modfile.IsDirectoryPath(r.New.Path) {
+			local[r.Old.Path] = true
+		}
+	}
+	var paths []string
+	for _, r := range f.Require {
+		paths = append(paths, r.Mod.Path)
+	}
+
+	gosum := filepath.Join(dir, "go.sum")
+	data, err = os.ReadFile(gosum)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: malformed go.sum line", gosum, line)
+		}
+		paths = append(paths, fields[0])
+	}
+
+	paths = slices.DeleteFunc(paths, func(p string) bool { return local[p] })
+	slices.Sort(paths)
+	return slices.Compact(paths), nil
+}
+
+// DefaultGOPROXY is the GOPROXY of a build whose environment doesn't set one.
+const DefaultGOPROXY = "https://proxy.golang.org,direct"
+
+// repositoryHosts maps the module path prefixes served by a go-get redirect,
+// such as golang.org/x/sync, to the host of their repository.
+var repositoryHosts = []struct {
+	prefix string
+	host   string
+}{
+	{"cloud.google.com/go", "github.com"},
+	{"go.opencensus.io", "github.com"},
+	{"go.uber.org/", "github.com"},
+	{"golang.org/x/", "go.googlesource.com"},
+	{"google.golang.org/api", "github.com"},
+	{"google.golang.org/appengine", "github.com"},
+	{"google.golang.org/genproto", "github.com"},
+	{"google.golang.org/grpc", "github.com"},
+	{"google.golang.org/protobuf", "go.googlesource.com"},
+	{"gopkg.in/", "github.com"},
+}
+
+// vcsHosts serve the repository of a module at its path.
+var vcsHosts = []string{"bitbucket.org", "github.com", "gitlab.com"}
+
+// FetchHosts returns the hosts the go command connects to when it downloads
+// module path through proxy, an entry of GOPROXY: the host of the proxy, or
+// for "direct" the host of the path, queried for its go-get redirect, and the
+// host of its repository. It returns false when the repository of a vanity
+// path is unknown or the entry can't download modules.
+func FetchHosts(path, proxy string) ([]string, bool) {
+	switch proxy {
+	case "off", "":
+		return nil, false
+	case "direct":
+	default:
+		u, err := url.Parse(proxy)
+		if err != nil || u.Hostname() == "" {
+			return nil, false
+		}
+		return []string{u.Hostname()}, true
+	}
+	host, _, _ := strings.Cut(path, "/")
+	if slices.Contains(vcsHosts, host) {
+		return []string{host}, true
+	}
+	for _, r := range repositoryHosts {
+		if strings.HasPrefix(path, r.prefix) {
+			return []string{host, r.host}, true
+		}
+	}
+	return nil, false
+}
+
+// Uncovered returns the modules of the Go function in dir that a build with
+// goproxy can't download through the proxy: the hosts of no GOPROXY entry it
+// may use are all matched by l. The proxy only sees the host of a request, so
+// the hosts are matched like the inUrlList(host(), ...) session matcher.
+func Uncovered(dir string, l URLList, goproxy string) ([]string, error) {
+	paths, err := Modules(dir)
+	if err != nil {
+		return nil, err
+	}
+	var uncovered []string
+	for _, p := range paths {
+		if !covered(p, l, goproxy) {
+			uncovered = append(uncovered, p)
+		}
+	}
+	return uncovered, nil
+}
+
+// covered reports whether l allows the download of path with goproxy. The go
+// command only tries the next entry of a comma separated list when a proxy
+// answers 404 or 410, not when the Secure Web Proxy denies the connection,
+// so only entries after a pipe are tried after a denied one.
+func covered(path string, l URLList, goproxy string) bool {
+	for goproxy != "" {
+		entry, rest := goproxy, ""
+		sep := byte(',')
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			entry, rest, sep = goproxy[:i], goproxy[i+1:], goproxy[i]
+		}
+		if hosts, ok := FetchHosts(path, entry); ok && !slices.ContainsFunc(hosts, func(h string) bool {
+			_, match := l.MatchHost(h)
+			return !match
+		}) {
+			return true
+		}
+		if sep != '|' {
+			return false
+		}
+		goproxy = rest
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const examplesDir = "../../../examples"
+
+// TestExamplesURLListCoverage checks that the url_lists of every example cover the modules of its function.
+func TestExamplesURLListCoverage(t *testing.T) {
+	for _, tt := range []struct {
+		example  string
+		function string
+	}{
+		{"secure_cloud_function_bigquery_trigger", "functions/bq-to-cf"},
+		{"secure_cloud_function_internal_server", "function"},
+		{"secure_cloud_function_with_sql", "functions/cf-to-sql"},
+	} {
+		t.Run(tt.example, func(t *testing.T) {
+			l, err := URLListFromTerraform(filepath.Join(examplesDir, tt.example, "main.tf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			uncovered, err := Uncovered(filepath.Join(examplesDir, tt.example, tt.function), l, DefaultGOPROXY)
+			if assert.NoError(t, err) {
+				assert.Empty(t, uncovered, "url_lists of %s should cover every module of the function", tt.example)
+			}
+		})
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", `module example.com/cloudsql
+
+go 1.21
+
+require (
+	cloud.google.com/go/cloudsqlconn v1.2.3
+	github.com/go-sql-driver/mysql v1.7.1
+	example.com/local v0.0.0
+)
+
+replace example.com/local => ../local
+`)
+	writeFile(t, dir, "go.sum", `cloud.google.com/go/cloudsqlconn v1.2.3 h1:abc=
+cloud.google.com/go/cloudsqlconn v1.2.3/go.mod h1:def=
+go.uber.org/zap v1.10.0/go.mod h1:ghi=
+gopkg.in/yaml.v3 v3.0.1/go.mod h1:jkl=
+`)
+
+	modules, err := Modules(dir)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"cloud.google.com/go/cloudsqlconn",
+			"github.com/go-sql-driver/mysql",
+			"go.uber.org/zap",
+			"gopkg.in/yaml.v3",
+		}, modules)
+	}
+
+	l, err := NewURLList([]string{"*google.com/go*", "*go.uber.org/zap", "github.com/googleapis/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncovered, err := Uncovered(dir, l, "direct")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"gopkg.in/yaml.v3"}, uncovered)
+	}
+
+	writeFile(t, dir, "go.sum", "cloud.google.com/go/cloudsqlconn\n")
+	_, err = Modules(dir)
+	assert.ErrorContains(t, err, "go.sum:1: malformed go.sum line")
+}
+
+func TestUncoveredFetchHosts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", `module example.com/function
+
+go 1.21
+
+require golang.org/x/sync v0.6.0
+`)
+	tests := []struct {
+		name    string
+		values  []string
+		goproxy string
+		want    []string
+	}{
+		{name: "vanity path without its repository", values: []string{"*golang.org/x*"}, goproxy: "direct", want: []string{"golang.org/x/sync"}},
+		{name: "vanity path and its repository", values: []string{"*golang.org/x*", "go.googlesource.com"}, goproxy: "direct"},
+		{name: "module proxy", values: []string{"proxy.golang.org"}, goproxy: DefaultGOPROXY},
+		{name: "module path without the module proxy", values: []string{"*golang.org/x*", "go.googlesource.com"}, goproxy: "https://goproxy.example.com,direct", want: []string{"golang.org/x/sync"}},
+		{name: "direct after a denied proxy", values: []string{"golang.org", "go.googlesource.com"}, goproxy: "https://goproxy.example.com|direct"},
+		{name: "off", values: []string{"*"}, goproxy: "off", want: []string{"golang.org/x/sync"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewURLList(tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			uncovered, err := Uncovered(dir, l, tt.goproxy)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, uncovered)
+			}
+		})
+	}
+}
+
+func TestFetchHosts(t *testing.T) {
+	tests := []struct {
+		path, proxy string
+		want        []string
+		ok          bool
+	}{
+		{path: "golang.org/x/sync", proxy: "https://proxy.golang.org", want: []string{"proxy.golang.org"}, ok: true},
+		{path: "golang.org/x/sync", proxy: "direct", want: []string{"golang.org", "go.googlesource.com"}, ok: true},
+		{path: "google.golang.org/grpc", proxy: "direct", want: []string{"google.golang.org", "github.com"}, ok: true},
+		{path: "github.com/google/uuid", proxy: "direct", want: []string{"github.com"}, ok: true},
+		{path: "example.com/vanity", proxy: "direct"},
+		{path: "github.com/google/uuid", proxy: "off"},
+	}
+	for _, tt := range tests {
+		hosts, ok := FetchHosts(tt.path, tt.proxy)
+		assert.Equal(t, tt.ok, ok, "%s through %s", tt.path, tt.proxy)
+		assert.Equal(t, tt.want, hosts, "%s through %s", tt.path, tt.proxy)
+	}
+}
+
+func TestURLListFromTerraform(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "main.tf", `module "secure_web_proxy" {
+  source  = "GoogleCloudPlatform/cloud-functions/google//modules/secure-web-proxy"
+  version = "~> 0.6"
+
+  url_lists = [
+    "*google.com/go*",
+    "*golang.org/x*",
+  ]
+}
+`)
+	l, err := URLListFromTerraform(filepath.Join(dir, "main.tf"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"*google.com/go*", "*golang.org/x*"}, l.Values())
+	}
+
+	writeFile(t, dir, "main.tf", `module "secure_web_proxy" {
+  source    = "../../modules/secure-web-proxy"
+  url_lists = var.url_lists
+}
+`)
+	_, err = URLListFromTerraform(filepath.Join(dir, "main.tf"))
+	assert.Error(t, err)
+
+	writeFile(t, dir, "main.tf", `module "other" {
+  source = "../../modules/secure-cloud-function"
+}
+`)
+	_, err = URLListFromTerraform(filepath.Join(dir, "main.tf"))
+	assert.ErrorContains(t, err, "no module call with source modules/secure-web-proxy")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode"
+)
+
+// Request is the part of a connection a session matcher can inspect.
+type Request struct {
+	Host           string
+	SourceIP       netip.Addr
+	ServiceAccount string
+}
+
+// Matcher is a compiled session matcher.
+//
+// It supports the subset of CEL used by Secure Web Proxy rules:
+// host(), source.ip, inUrlList(host(), 'URL_LIST'), inIpRange(source.ip, 'CIDR'),
+// source.matchServiceAccount('EMAIL'), the string methods startsWith, endsWith
+// and contains, the == and != comparisons, and the !, && and || operators.
+type Matcher struct {
+	expr string
+	root node
+}
+
+// env is what a matcher is evaluated against.
+type env struct {
+	req   Request
+	lists map[string]URLList
+}
+
+type node func(env) (any, error)
+
+// CompileSessionMatcher parses a session matcher expression.
+func CompileSessionMatcher(expr string) (*Matcher, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("session matcher %q: %w", expr, err)
+	}
+	p := &parser{toks: toks}
+	root, err := p.or()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session matcher %q: %w", expr, err)
+	}
+	return &Matcher{expr: expr, root: root}, nil
+}
+
+func (m *Matcher) String() string {
+	return m.expr
+}
+
+// Match evaluates the matcher for r. lists maps URL list resource names to their content.
+func (m *Matcher) Match(r Request, lists map[string]URLList) (bool, error) {
+	v, err := m.root(env{req: r, lists: lists})
+	if err != nil {
+		return false, fmt.Errorf("session matcher %q: %w", m.expr, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("session matcher %q does not evaluate to a bool", m.expr)
+	}
+	return b, nil
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexRune(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{tokString, s[i+1 : i+1+end]})
+			i += end + 2
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, s[i:j]})
+			i = j
+		default:
+			if i+1 < len(s) {
+				if two := s[i : i+2]; two == "&&" || two == "||" || two == "==" || two == "!=" {
+					toks = append(toks, token{tokPunct, two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("()!,.", c) {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, token{tokPunct, string(c)})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{tokPunct, "end of expression"}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if !p.done() && p.toks[p.pos].kind == kind && p.toks[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(tokPunct, text) {
+		return fmt.Errorf("expected %q, found %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokPunct, "||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokPunct, "&&") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+	return left, nil
+}
+
+// logical short-circuits like CEL: || stops at the first true operand, && at the first false one.
+func logical(left, right node, or bool) node {
+	return func(e env) (any, error) {
+		for _, n := range []node{left, right} {
+			b, err := evalBool(n, e)
+			if err != nil {
+				return nil, err
+			}
+			if b == or {
+				return or, nil
+			}
+		}
+		return !or, nil
+	}
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!="} {
+		if !p.accept(tokPunct, op) {
+			continue
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e env) (any, error) {
+			l, err := left(e)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right(e)
+			if err != nil {
+				return nil, err
+			}
+			return (l == r) == (op == "=="), nil
+		}, nil
+	}
+	return left, nil
+}
+
+// unary parses a negation, which binds tighter than the comparisons like in CEL:
+// !a == b is (!a) == b.
+func (p *parser) unary() (node, error) {
+	if p.accept(tokPunct, "!") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e env) (any, error) {
+			b, err := evalBool(n, e)
+			return !b, err
+		}, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses a primary expression followed by string method calls.
+func (p *parser) postfix() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokPunct, ".") {
+		method := p.peek()
+		if method.kind != tokIdent {
+			return nil, fmt.Errorf("expected method name, found %q", method.text)
+		}
+		p.pos++
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		n, err = stringMethod(n, method.text, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch {
+	case p.accept(tokPunct, "("):
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case t.kind == tokString:
+		p.pos++
+		return func(env) (any, error) { return t.text, nil }, nil
+	case t.kind != tokIdent:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	p.pos++
+
+	switch t.text {
+	case "true", "false":
+		return func(env) (any, error) { return t.text == "true", nil }, nil
+	case "source":
+		return p.source()
+	}
+	args, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+	switch t.text {
+	case "host":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("host() takes no arguments")
+		}
+		return func(e env) (any, error) { return strings.ToLower(e.req.Host), nil }, nil
+	case "inUrlList":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("inUrlList() takes 2 arguments")
+		}
+		return func(e env) (any, error) {
+			host, err := evalString(args[0], e)
+			if err != nil {
+				return nil, err
+			}
+			name, err := evalString(args[1], e)
+			if err != nil {
+				return nil, err
+			}
+			l, ok := e.lists[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown URL list %s", name)
+			}
+			_, ok = l.MatchHost(host)
+			return ok, nil
+		}, nil
+	case "inIpRange":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("inIpRange() takes 2 arguments")
+		}
+		return func(e env) (any, error) {
+			ip, err := evalString(args[0], e)
+			if err != nil {
+				return nil, err
+			}
+			cidr, err := evalString(args[1], e)
+			if err != nil {
+				return nil, err
+			}
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				return false, nil
+			}
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP range %q", cidr)
+			}
+			return prefix.Contains(addr), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported function %s()", t.text)
+}
+
+// source parses the attributes and functions of the source of the request.
+func (p *parser) source() (node, error) {
+	if err := p.expect("."); err != nil {
+		return nil, err
+	}
+	attr := p.peek()
+	p.pos++
+	switch attr.text {
+	case "ip":
+		return func(e env) (any, error) {
+			if !e.req.SourceIP.IsValid() {
+				return "", nil
+			}
+			return e.req.SourceIP.String(), nil
+		}, nil
+	case "matchServiceAccount":
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != 1 {
+			return nil, fmt.Errorf("source.matchServiceAccount() takes 1 argument")
+		}
+		return func(e env) (any, error) {
+			sa, err := evalString(args[0], e)
+			if err != nil {
+				return nil, err
+			}
+			return e.req.ServiceAccount != "" && e.req.ServiceAccount == sa, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute source.%s", attr.text)
+}
+
+func (p *parser) args() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	if p.accept(tokPunct, ")") {
+		return args, nil
+	}
+	for {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, n)
+		if p.accept(tokPunct, ")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func stringMethod(target node, method string, args []node) (node, error) {
+	var f func(s, arg string) bool
+	switch method {
+	case "startsWith":
+		f = strings.HasPrefix
+	case "endsWith":
+		f = strings.HasSuffix
+	case "contains":
+		f = strings.Contains
+	default:
+		return nil, fmt.Errorf("unsupported method %s()", method)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s() takes 1 argument", method)
+	}
+	return func(e env) (any, error) {
+		s, err := evalString(target, e)
+		if err != nil {
+			return nil, err
+		}
+		arg, err := evalString(args[0], e)
+		if err != nil {
+			return nil, err
+		}
+		return f(s, arg), nil
+	}, nil
+}
+
+func evalBool(n node, e env) (bool, error) {
+	v, err := n(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a bool, found %q", v)
+	}
+	return b, nil
+}
+
+func evalString(n node, e env) (string, error) {
+	v, err := n(e)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, found %v", v)
+	}
+	return s, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const urlListName = "projects/prj-restricted-shared/locations/us-west1/urlLists/swp-url-lists"
+
+func testLists(t *testing.T) map[string]URLList {
+	l, err := NewURLList([]string{"*google.com/go*", "*golang.org/x*", "*github.com/GoogleCloudPlatform*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]URLList{urlListName: l}
+}
+
+func TestSessionMatcher(t *testing.T) {
+	lists := testLists(t)
+	req := Request{
+		Host:           "cloud.google.com",
+		SourceIP:       netip.MustParseAddr("10.0.0.4"),
+		ServiceAccount: "sa-cloud-function@prj-secure-cloud-function.iam.gserviceaccount.com",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"inUrlList(host(), '" + urlListName + "')", true},
+		{`inUrlList(host(), "` + urlListName + `")`, true},
+		{"host() == 'cloud.google.com'", true},
+		{"host() != 'cloud.google.com'", false},
+		{"host().endsWith('.google.com')", true},
+		{"host().startsWith('storage.')", false},
+		{"host().contains('google')", true},
+		{"inIpRange(source.ip, '10.0.0.0/28')", true},
+		{"inIpRange(source.ip, '10.129.0.0/23')", false},
+		{"source.matchServiceAccount('sa-cloud-function@prj-secure-cloud-function.iam.gserviceaccount.com')", true},
+		{"source.matchServiceAccount('other@prj-secure-cloud-function.iam.gserviceaccount.com')", false},
+		{"!inIpRange(source.ip, '10.129.0.0/23') && host() == 'cloud.google.com'", true},
+		{"host() == 'example.com' || inIpRange(source.ip, '10.0.0.0/28')", true},
+		{"host() == 'example.com' || (true && !false && host() == 'github.com')", false},
+		{"true || inUrlList(host(), 'projects/p/locations/l/urlLists/missing')", true},
+		{"!false == 'cloud.google.com'", false},
+		{"!(false == 'cloud.google.com')", true},
+		{"!host().contains('github') && host() != 'github.com'", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m, err := CompileSessionMatcher(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.Match(req, lists)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSessionMatcherErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"host(",
+		"host() ==",
+		"inUrlList(host())",
+		"'unterminated",
+		"host() > 'a'",
+		"request.path()",
+		"source.tag",
+		"host().matches('.*')",
+		"host() host()",
+	} {
+		_, err := CompileSessionMatcher(expr)
+		assert.Error(t, err, expr)
+	}
+
+	for _, expr := range []string{
+		"host()",
+		"inUrlList(host(), 'projects/p/locations/l/urlLists/missing')",
+		"host().endsWith(true)",
+		"!host() == 'github.com'",
+	} {
+		m, err := CompileSessionMatcher(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = m.Match(Request{Host: "github.com"}, testLists(t))
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	rules, err := ParseRules([]byte(`[
+  {"name": "deny-build-pool", "enabled": true, "priority": 0, "basicProfile": "DENY", "sessionMatcher": "inIpRange(source.ip, '10.3.0.0/24') && host() == 'github.com'"},
+  {"name": "swp-security-policy-rule", "enabled": true, "priority": 1, "basicProfile": "ALLOW", "sessionMatcher": "inUrlList(host(), '` + urlListName + `')"},
+  {"name": "disabled", "enabled": false, "priority": 2, "basicProfile": "ALLOW", "sessionMatcher": "true"}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPolicy(rules, testLists(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		want Verdict
+	}{
+		{"url list", Request{Host: "golang.org", SourceIP: netip.MustParseAddr("10.0.0.4")}, Verdict{Allowed: true, Rule: "swp-security-policy-rule"}},
+		{"deny first", Request{Host: "github.com", SourceIP: netip.MustParseAddr("10.3.0.9")}, Verdict{Rule: "deny-build-pool"}},
+		{"default deny", Request{Host: "example.com", SourceIP: netip.MustParseAddr("10.0.0.4")}, Verdict{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Evaluate(tt.req)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	_, err = NewPolicy([]Rule{{Name: "bad", Enabled: true, BasicProfile: ProfileAllow, SessionMatcher: "host("}}, nil)
+	assert.ErrorContains(t, err, "rule bad")
+}
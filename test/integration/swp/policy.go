@@ -0,0 +1,101 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+const (
+	ProfileAllow = "ALLOW"
+	ProfileDeny  = "DENY"
+)
+
+// Rule is a gateway security policy rule, as returned by
+// `gcloud network-security gateway-security-policies rules list`.
+type Rule struct {
+	Name           string `json:"name,omitempty"`
+	Description    string `json:"description,omitempty"`
+	Enabled        bool   `json:"enabled,omitempty"`
+	Priority       int    `json:"priority,omitempty"`
+	BasicProfile   string `json:"basicProfile,omitempty"`
+	SessionMatcher string `json:"sessionMatcher,omitempty"`
+}
+
+// ParseRules decodes the JSON document returned by `gcloud network-security gateway-security-policies rules list`.
+func ParseRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing gateway security policy rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Policy is a gateway security policy together with the URL lists its rules reference.
+type Policy struct {
+	rules []compiledRule
+	lists map[string]URLList
+}
+
+type compiledRule struct {
+	Rule
+	matcher *Matcher
+}
+
+// Verdict is the result of evaluating a request against a policy.
+type Verdict struct {
+	Allowed bool
+	// Rule is the name of the matching rule, empty when no rule matches and the request is denied by default.
+	Rule string
+}
+
+// NewPolicy compiles the session matchers of the enabled rules.
+// lists maps URL list resource names, such as projects/P/locations/L/urlLists/N, to their content.
+func NewPolicy(rules []Rule, lists map[string]URLList) (*Policy, error) {
+	p := &Policy{lists: lists}
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		if r.BasicProfile != ProfileAllow && r.BasicProfile != ProfileDeny {
+			return nil, fmt.Errorf("rule %s: unsupported basic profile %q", r.Name, r.BasicProfile)
+		}
+		m, err := CompileSessionMatcher(r.SessionMatcher)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		p.rules = append(p.rules, compiledRule{Rule: r, matcher: m})
+	}
+	// Rules are evaluated from the lowest priority value.
+	slices.SortStableFunc(p.rules, func(a, b compiledRule) int { return a.Priority - b.Priority })
+	return p, nil
+}
+
+// Evaluate returns the verdict of the first rule whose session matcher matches r.
+// Requests that match no rule are denied.
+func (p *Policy) Evaluate(r Request) (Verdict, error) {
+	for _, rule := range p.rules {
+		ok, err := rule.matcher.Match(r, p.lists)
+		if err != nil {
+			return Verdict{}, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if ok {
+			return Verdict{Allowed: rule.BasicProfile == ProfileAllow, Rule: rule.Name}, nil
+		}
+	}
+	return Verdict{}, nil
+}
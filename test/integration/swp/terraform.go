@@ -0,0 +1,74 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const moduleSource = "modules/secure-web-proxy"
+
+// URLListFromTerraform reads the url_lists argument of the secure-web-proxy module
+// call in a Terraform file. The argument must be a literal list of strings.
+func URLListFromTerraform(path string) (URLList, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return URLList{}, err
+	}
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return URLList{}, diags
+	}
+	for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "module" {
+			continue
+		}
+		source, ok := b.Body.Attributes["source"]
+		if !ok {
+			continue
+		}
+		v, diags := source.Expr.Value(nil)
+		if diags.HasErrors() || v.Type() != cty.String || !strings.HasSuffix(v.AsString(), moduleSource) {
+			continue
+		}
+		attr, ok := b.Body.Attributes["url_lists"]
+		if !ok {
+			return URLList{}, fmt.Errorf("%s: module %s has no url_lists", path, b.Labels[0])
+		}
+		v, diags = attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return URLList{}, diags
+		}
+		if !v.CanIterateElements() {
+			return URLList{}, fmt.Errorf("%s: url_lists of module %s is not a list", path, b.Labels[0])
+		}
+		var values []string
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			if e.Type() != cty.String || e.IsNull() {
+				return URLList{}, fmt.Errorf("%s: url_lists of module %s must only contain strings", path, b.Labels[0])
+			}
+			values = append(values, e.AsString())
+		}
+		return NewURLList(values)
+	}
+	return URLList{}, fmt.Errorf("%s: no module call with source %s", path, moduleSource)
+}
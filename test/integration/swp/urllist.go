@@ -0,0 +1,127 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package swp evaluates Secure Web Proxy policies offline.
+package swp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URLList is a Secure Web Proxy URL list.
+//
+// Each value is a host pattern optionally followed by a path pattern, such as
+// "*github.com/GoogleCloudPlatform*". The only wildcard is "*", which matches
+// any sequence of characters within the host or within the path.
+// Hosts match case-insensitively, paths case-sensitively, and a value without
+// a path matches every path of the host.
+type URLList struct {
+	entries []entry
+}
+
+type entry struct {
+	value string
+	host  string
+	path  string
+}
+
+// NewURLList validates the values of a URL list.
+func NewURLList(values []string) (URLList, error) {
+	l := URLList{}
+	for _, v := range values {
+		if v == "" || strings.ContainsAny(v, " \t\n?#") || strings.Contains(v, "://") {
+			return URLList{}, fmt.Errorf("invalid URL list value %q", v)
+		}
+		host, p, _ := strings.Cut(v, "/")
+		if host == "" {
+			return URLList{}, fmt.Errorf("invalid URL list value %q: missing host", v)
+		}
+		l.entries = append(l.entries, entry{value: v, host: strings.ToLower(host), path: p})
+	}
+	return l, nil
+}
+
+// Values returns the values of the list.
+func (l URLList) Values() []string {
+	values := make([]string, 0, len(l.entries))
+	for _, e := range l.entries {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+// Match reports the first value of the list that matches url.
+// The url may carry a scheme, port, query or fragment; they are ignored.
+func (l URLList) Match(url string) (string, bool) {
+	host, p := splitURL(url)
+	for _, e := range l.entries {
+		if !glob(e.host, host) {
+			continue
+		}
+		if e.path == "" || glob(e.path, p) {
+			return e.value, true
+		}
+	}
+	return "", false
+}
+
+// MatchHost reports the first value of the list whose host pattern matches host.
+// It mirrors the inUrlList(host(), ...) session matcher, which only sees the host
+// of a request when TLS inspection is disabled.
+func (l URLList) MatchHost(host string) (string, bool) {
+	host = strings.ToLower(host)
+	for _, e := range l.entries {
+		if glob(e.host, host) {
+			return e.value, true
+		}
+	}
+	return "", false
+}
+
+// splitURL returns the lower-cased host and the path, without its leading slash, of url.
+func splitURL(url string) (string, string) {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	}
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	host, p, _ := strings.Cut(url, "/")
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	return strings.ToLower(host), p
+}
+
+// glob reports whether s matches pattern, where "*" matches any sequence of characters.
+func glob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLListMatch(t *testing.T) {
+	l, err := NewURLList([]string{
+		"*google.com/go*",
+		"*github.com/GoogleCloudPlatform*",
+		"*golang.org/x*",
+		"*google.golang.org/*",
+		"*go.opencensus.io",
+		"deb.debian.org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"cloud.google.com/go/storage", "*google.com/go*"},
+		{"https://cloud.google.com/go/storage?go-get=1", "*google.com/go*"},
+		{"github.com/GoogleCloudPlatform/functions-framework-go", "*github.com/GoogleCloudPlatform*"},
+		{"GITHUB.COM/GoogleCloudPlatform/functions-framework-go", "*github.com/GoogleCloudPlatform*"},
+		{"golang.org/x/sync", "*golang.org/x*"},
+		{"google.golang.org/api", "*google.golang.org/*"},
+		{"go.opencensus.io", "*go.opencensus.io"},
+		{"go.opencensus.io/trace", "*go.opencensus.io"},
+		{"https://deb.debian.org:443/debian/dists", "deb.debian.org"},
+		{"github.com/googlecloudplatform/functions-framework-go", ""},
+		{"github.com/go-sql-driver/mysql", ""},
+		{"cloud.google.com/storage", ""},
+		{"security.debian.org/debian-security", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, ok := l.Match(tt.url)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want != "", ok)
+		})
+	}
+
+	got, ok := l.MatchHost("github.com")
+	assert.True(t, ok)
+	assert.Equal(t, "*github.com/GoogleCloudPlatform*", got)
+	_, ok = l.MatchHost("security.debian.org")
+	assert.False(t, ok)
+}
+
+func TestNewURLListInvalid(t *testing.T) {
+	for _, v := range []string{"", "https://github.com/*", "/path", "github.com/a b", "github.com/*?x=1"} {
+		_, err := NewURLList([]string{v})
+		assert.Error(t, err, v)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+		{"a*c", "abbbc", true},
+		{"a*a", "a", false},
+		{"*b*", "abc", true},
+		{"*b*d", "abcbd", true},
+		{"*b*d", "abc", false},
+	} {
+		assert.Equal(t, tt.want, glob(tt.pattern, tt.s), "%s ~ %s", tt.pattern, tt.s)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ReportDirEnv is the environment variable with the directory test reports are written to.
+const ReportDirEnv = "TEST_REPORT_DIR"
+
+// Class is the outcome of classifying a Terraform error.
+type Class string
+
+const (
+	ClassRetryable Class = "retryable"
+	ClassPermanent Class = "permanent"
+	ClassUnknown   Class = "unknown"
+)
+
+// errorLine matches the lines of a Terraform log that report an error.
+var errorLine = regexp.MustCompile(`\bError\b`)
+
+// Classification is the class of an error log and the pattern that decided it.
+type Classification struct {
+	Class   Class
+	Pattern string
+	Reason  string
+}
+
+// PatternStats is the number of errors a pattern matched.
+type PatternStats struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+	Class   Class  `json:"class"`
+	Hits    int    `json:"hits"`
+}
+
+// Summary is the number of errors classified during a run.
+type Summary struct {
+	Patterns []PatternStats `json:"patterns"`
+	// Unknown are the error lines no pattern matched.
+	Unknown []string `json:"unknown"`
+}
+
+type classifierPattern struct {
+	re     *regexp.Regexp
+	reason string
+	class  Class
+}
+
+// Classifier labels Terraform error logs as retryable, permanent or unknown
+// and counts how often each pattern matched.
+type Classifier struct {
+	// patterns are the retryable patterns followed by the permanent ones, each sorted.
+	patterns []classifierPattern
+	mu       sync.Mutex
+	hits     map[string]int
+	unknown  []string
+}
+
+// NewClassifier compiles the retryable and permanent patterns. A log matching
+// both is retryable, as it is what tft retries.
+func NewClassifier(retryable, permanent map[string]string) (*Classifier, error) {
+	c := &Classifier{hits: map[string]int{}}
+	for _, set := range []struct {
+		class    Class
+		patterns map[string]string
+	}{{ClassRetryable, retryable}, {ClassPermanent, permanent}} {
+		keys := make([]string, 0, len(set.patterns))
+		for p := range set.patterns {
+			keys = append(keys, p)
+		}
+		sort.Strings(keys)
+		for _, p := range keys {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("error compiling %s error pattern %q: %w", set.class, p, err)
+			}
+			c.patterns = append(c.patterns, classifierPattern{re: re, reason: set.patterns[p], class: set.class})
+		}
+	}
+	return c, nil
+}
+
+// NewTransientErrorClassifier returns a classifier of RetryableTransientErrors and PermanentErrors.
+func NewTransientErrorClassifier() *Classifier {
+	c, err := NewClassifier(RetryableTransientErrors, PermanentErrors)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Classify returns the class of log and counts a hit for every pattern it matches.
+func (c *Classifier) Classify(log string) Classification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := Classification{Class: ClassUnknown}
+	for _, p := range c.patterns {
+		if !p.re.MatchString(log) {
+			continue
+		}
+		c.hits[p.re.String()]++
+		if result.Class == ClassUnknown {
+			result = Classification{Class: p.class, Pattern: p.re.String(), Reason: p.reason}
+		}
+	}
+	if result.Class == ClassUnknown {
+		c.unknown = append(c.unknown, strings.TrimSpace(log))
+	}
+	return result
+}
+
+// Summary returns the hits of every pattern and the unknown errors.
+func (c *Classifier) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Summary{Patterns: make([]PatternStats, 0, len(c.patterns)), Unknown: append([]string{}, c.unknown...)}
+	for _, p := range c.patterns {
+		s.Patterns = append(s.Patterns, PatternStats{Pattern: p.re.String(), Reason: p.reason, Class: p.class, Hits: c.hits[p.re.String()]})
+	}
+	return s
+}
+
+// Unused returns the patterns that never matched.
+func (s Summary) Unused() []string {
+	var unused []string
+	for _, p := range s.Patterns {
+		if p.Hits == 0 {
+			unused = append(unused, p.Pattern)
+		}
+	}
+	return unused
+}
+
+// Write writes the summary as a table.
+func (s Summary) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HITS\tCLASS\tREASON\tPATTERN")
+	for _, p := range s.Patterns {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", p.Hits, p.Class, p.Reason, p.Pattern)
+	}
+	fmt.Fprintf(tw, "%d\t%s\t\t\n", len(s.Unknown), ClassUnknown)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, u := range s.Unknown {
+		if _, err := fmt.Fprintf(w, "unknown: %s\n", u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Logger returns a logger that classifies the error lines logged by Terraform
+// before passing them to next. When t finishes the summary is logged and, if
+// ReportDirEnv is set, written to <test>.transient-errors.json in that directory.
+func (c *Classifier) Logger(t testing.TB, next *logger.Logger) *logger.Logger {
+	t.Cleanup(func() {
+		s := c.Summary()
+		var b strings.Builder
+		if err := s.Write(&b); err == nil {
+			t.Logf("transient error summary:\n%s", b.String())
+		}
+		if dir := os.Getenv(ReportDirEnv); dir != "" {
+			if err := s.save(dir, t.Name()); err != nil {
+				t.Errorf("error writing transient error summary: %v", err)
+			}
+		}
+	})
+	return logger.New(classifyingLogger{c: c, next: next})
+}
+
+func (s Summary) save(dir, test string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(test)
+	return os.WriteFile(filepath.Join(dir, name+".transient-errors.json"), data, 0644)
+}
+
+type classifyingLogger struct {
+	c    *Classifier
+	next *logger.Logger
+}
+
+func (l classifyingLogger) Logf(t terratesting.TestingT, format string, args ...interface{}) {
+	if line := fmt.Sprintf(format, args...); errorLine.MatchString(line) {
+		l.c.Classify(line)
+	}
+	l.next.Logf(t, format, args...)
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const errorCorpus = "testdata/errors"
+
+// TestClassifierCorpus classifies every sample log under testdata/errors/<class>.
+// Add a sample when a pattern is added, and retire patterns the corpus no longer needs.
+func TestClassifierCorpus(t *testing.T) {
+	c := NewTransientErrorClassifier()
+	logs, err := filepath.Glob(filepath.Join(errorCorpus, "*", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, logs)
+	for _, path := range logs {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".log"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Class(filepath.Base(filepath.Dir(path)))
+			got := c.Classify(string(data))
+			assert.Equal(t, want, got.Class, "pattern %q", got.Pattern)
+			if want != ClassUnknown {
+				assert.NotEmpty(t, got.Reason)
+			}
+		})
+	}
+	assert.Empty(t, c.Summary().Unused(), "every pattern should match a sample log")
+}
+
+func TestNewClassifier(t *testing.T) {
+	_, err := NewClassifier(map[string]string{"(": "invalid"}, nil)
+	assert.Error(t, err)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		want    Class
+		pattern string
+	}{
+		{
+			name:    "retryable",
+			log:     "Error: googleapi: Error 409: There were concurrent policy changes.",
+			want:    ClassRetryable,
+			pattern: ".*Error 409.*There were concurrent policy changes.*",
+		},
+		{
+			name:    "retryable wins over permanent",
+			log:     "Error 409: There were concurrent policy changes, the binding already exists",
+			want:    ClassRetryable,
+			pattern: ".*Error 409.*There were concurrent policy changes.*",
+		},
+		{
+			name:    "permanent",
+			log:     "Error 409: the repository already exists",
+			want:    ClassPermanent,
+			pattern: ".*Error 409.*already exists.*",
+		},
+		{
+			name: "unknown",
+			log:  "Error code 13, message: An internal error has occurred.",
+			want: ClassUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTransientErrorClassifier().Classify(tt.log)
+			assert.Equal(t, tt.want, got.Class)
+			assert.Equal(t, tt.pattern, got.Pattern)
+		})
+	}
+}
+
+func TestClassifierSummary(t *testing.T) {
+	c, err := NewClassifier(
+		map[string]string{".*rateLimitExceeded.*": "Rate limit exceeded."},
+		map[string]string{".*already exists.*": "Resource already exists."},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Classify("Error 403: rateLimitExceeded")
+	c.Classify("Error 403: rateLimitExceeded")
+	c.Classify("Error 500: backendError")
+
+	s := c.Summary()
+	assert.Equal(t, []PatternStats{
+		{Pattern: ".*rateLimitExceeded.*", Reason: "Rate limit exceeded.", Class: ClassRetryable, Hits: 2},
+		{Pattern: ".*already exists.*", Reason: "Resource already exists.", Class: ClassPermanent, Hits: 0},
+	}, s.Patterns)
+	assert.Equal(t, []string{"Error 500: backendError"}, s.Unknown)
+	assert.Equal(t, []string{".*already exists.*"}, s.Unused())
+
+	var b strings.Builder
+	assert.NoError(t, s.Write(&b))
+	assert.Contains(t, b.String(), "2     retryable  Rate limit exceeded.")
+	assert.Contains(t, b.String(), "unknown: Error 500: backendError")
+}
+
+func TestClassifierLogger(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ReportDirEnv, dir)
+	c := NewTransientErrorClassifier()
+	t.Run("run", func(t *testing.T) {
+		l := c.Logger(t, logger.Discard)
+		l.Logf(t, "module.network.google_compute_network.network: Creating...")
+		l.Logf(t, "%s", "│ Error: googleapi: Error 403: Quota exceeded, rateLimitExceeded")
+	})
+	s := c.Summary()
+	assert.Empty(t, s.Unknown, "only error lines should be classified")
+	for _, p := range s.Patterns {
+		if p.Pattern == ".*rateLimitExceeded.*" {
+			assert.Equal(t, 1, p.Hits)
+		}
+	}
+	_, err := os.Stat(filepath.Join(dir, "TestClassifierLogger_run.transient-errors.json"))
+	assert.NoError(t, err)
+}
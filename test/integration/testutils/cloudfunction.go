@@ -0,0 +1,182 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// CloudFunction is the output of `gcloud functions describe --gen2`.
+// It mirrors the fields of the Cloud Functions v2 Function resource used by the tests.
+type CloudFunction struct {
+	Name          string            `json:"name,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Environment   string            `json:"environment,omitempty"`
+	State         string            `json:"state,omitempty"`
+	UpdateTime    string            `json:"updateTime,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	KMSKeyName    string            `json:"kmsKeyName,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	BuildConfig   BuildConfig       `json:"buildConfig,omitempty"`
+	ServiceConfig ServiceConfig     `json:"serviceConfig,omitempty"`
+	EventTrigger  *EventTrigger     `json:"eventTrigger,omitempty"`
+}
+
+type BuildConfig struct {
+	Build                string            `json:"build,omitempty"`
+	Runtime              string            `json:"runtime,omitempty"`
+	EntryPoint           string            `json:"entryPoint,omitempty"`
+	DockerRepository     string            `json:"dockerRepository,omitempty"`
+	DockerRegistry       string            `json:"dockerRegistry,omitempty"`
+	WorkerPool           string            `json:"workerPool,omitempty"`
+	ServiceAccount       string            `json:"serviceAccount,omitempty"`
+	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
+	Source               Source            `json:"source,omitempty"`
+	SourceProvenance     SourceProvenance  `json:"sourceProvenance,omitempty"`
+}
+
+type Source struct {
+	StorageSource *StorageSource `json:"storageSource,omitempty"`
+	RepoSource    *RepoSource    `json:"repoSource,omitempty"`
+}
+
+type SourceProvenance struct {
+	ResolvedStorageSource *StorageSource `json:"resolvedStorageSource,omitempty"`
+	ResolvedRepoSource    *RepoSource    `json:"resolvedRepoSource,omitempty"`
+}
+
+type StorageSource struct {
+	Bucket     string `json:"bucket,omitempty"`
+	Object     string `json:"object,omitempty"`
+	Generation string `json:"generation,omitempty"`
+}
+
+type RepoSource struct {
+	ProjectID   string `json:"projectId,omitempty"`
+	RepoName    string `json:"repoName,omitempty"`
+	BranchName  string `json:"branchName,omitempty"`
+	TagName     string `json:"tagName,omitempty"`
+	CommitSha   string `json:"commitSha,omitempty"`
+	Dir         string `json:"dir,omitempty"`
+	InvertRegex bool   `json:"invertRegex,omitempty"`
+}
+
+type ServiceConfig struct {
+	Service                       string            `json:"service,omitempty"`
+	Revision                      string            `json:"revision,omitempty"`
+	URI                           string            `json:"uri,omitempty"`
+	TimeoutSeconds                int               `json:"timeoutSeconds,omitempty"`
+	AvailableMemory               string            `json:"availableMemory,omitempty"`
+	AvailableCPU                  string            `json:"availableCpu,omitempty"`
+	MaxInstanceCount              int               `json:"maxInstanceCount,omitempty"`
+	MinInstanceCount              int               `json:"minInstanceCount,omitempty"`
+	MaxInstanceRequestConcurrency int               `json:"maxInstanceRequestConcurrency,omitempty"`
+	EnvironmentVariables          map[string]string `json:"environmentVariables,omitempty"`
+	VPCConnector                  string            `json:"vpcConnector,omitempty"`
+	VPCConnectorEgressSettings    string            `json:"vpcConnectorEgressSettings,omitempty"`
+	IngressSettings               string            `json:"ingressSettings,omitempty"`
+	ServiceAccountEmail           string            `json:"serviceAccountEmail,omitempty"`
+	AllTrafficOnLatestRevision    bool              `json:"allTrafficOnLatestRevision,omitempty"`
+	SecurityLevel                 string            `json:"securityLevel,omitempty"`
+	SecretEnvironmentVariables    []SecretEnvVar    `json:"secretEnvironmentVariables,omitempty"`
+	SecretVolumes                 []SecretVolume    `json:"secretVolumes,omitempty"`
+}
+
+type SecretEnvVar struct {
+	Key       string `json:"key,omitempty"`
+	ProjectID string `json:"projectId,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+type SecretVolume struct {
+	MountPath string          `json:"mountPath,omitempty"`
+	ProjectID string          `json:"projectId,omitempty"`
+	Secret    string          `json:"secret,omitempty"`
+	Versions  []SecretVersion `json:"versions,omitempty"`
+}
+
+type SecretVersion struct {
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+type EventTrigger struct {
+	Trigger             string        `json:"trigger,omitempty"`
+	TriggerRegion       string        `json:"triggerRegion,omitempty"`
+	EventType           string        `json:"eventType,omitempty"`
+	EventFilters        []EventFilter `json:"eventFilters,omitempty"`
+	PubsubTopic         string        `json:"pubsubTopic,omitempty"`
+	ServiceAccountEmail string        `json:"serviceAccountEmail,omitempty"`
+	RetryPolicy         string        `json:"retryPolicy,omitempty"`
+	Channel             string        `json:"channel,omitempty"`
+}
+
+type EventFilter struct {
+	Attribute string `json:"attribute,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+}
+
+// ParseCloudFunction decodes the JSON document returned by `gcloud functions describe`.
+func ParseCloudFunction(data []byte) (CloudFunction, error) {
+	var cf CloudFunction
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return CloudFunction{}, fmt.Errorf("error parsing cloud function: %w", err)
+	}
+	return cf, nil
+}
+
+// DescribeCloudFunction runs `gcloud functions describe` for a gen2 function and decodes the result.
+func (g *GCloud) DescribeCloudFunction(t testing.TB, name, projectID, location string) CloudFunction {
+	op := g.Runf(t, "functions describe %s --project %s --gen2 --region %s", name, projectID, location)
+	cf, err := ParseCloudFunction([]byte(op.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+// EventFilter returns the event filter for the given attribute.
+func (e EventTrigger) EventFilter(attribute string) (EventFilter, bool) {
+	for _, f := range e.EventFilters {
+		if f.Attribute == attribute {
+			return f, true
+		}
+	}
+	return EventFilter{}, false
+}
+
+// SecretEnvironmentVariable returns the secret environment variable with the given key.
+func (s ServiceConfig) SecretEnvironmentVariable(key string) (SecretEnvVar, bool) {
+	for _, v := range s.SecretEnvironmentVariables {
+		if v.Key == key {
+			return v, true
+		}
+	}
+	return SecretEnvVar{}, false
+}
+
+// SecretVolume returns the secret volume mounted at the given path.
+func (s ServiceConfig) SecretVolume(mountPath string) (SecretVolume, bool) {
+	for _, v := range s.SecretVolumes {
+		if v.MountPath == mountPath {
+			return v, true
+		}
+	}
+	return SecretVolume{}, false
+}
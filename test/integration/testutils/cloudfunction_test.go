@@ -0,0 +1,128 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+const functionDescribePath = "../../../function_describe.json"
+
+func TestParseCloudFunction(t *testing.T) {
+	data, err := os.ReadFile(functionDescribePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := ParseCloudFunction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "projects/prj-secure-cloud-function-25de/locations/us-west1/functions/secure-cloud-function-bigquery", cf.Name)
+	assert.Equal(t, "ACTIVE", cf.State)
+	assert.Equal(t, "GEN_2", cf.Environment)
+
+	assert.Equal(t, "go121", cf.BuildConfig.Runtime)
+	assert.Equal(t, "HelloCloudFunction", cf.BuildConfig.EntryPoint)
+	if assert.NotNil(t, cf.BuildConfig.Source.StorageSource) {
+		assert.Equal(t, "gcf-v2-sources-97410184241-us-west1", cf.BuildConfig.Source.StorageSource.Bucket)
+	}
+	assert.Nil(t, cf.BuildConfig.Source.RepoSource)
+	if assert.NotNil(t, cf.BuildConfig.SourceProvenance.ResolvedStorageSource) {
+		assert.Equal(t, "1682514355580422", cf.BuildConfig.SourceProvenance.ResolvedStorageSource.Generation)
+	}
+
+	assert.Equal(t, "projects/prj-secure-cloud-function-25de/locations/us-west1/connectors/con-secure-cloud-function", cf.ServiceConfig.VPCConnector)
+	assert.Equal(t, "PRIVATE_RANGES_ONLY", cf.ServiceConfig.VPCConnectorEgressSettings)
+	assert.Equal(t, "ALLOW_INTERNAL_AND_GCLB", cf.ServiceConfig.IngressSettings)
+	assert.Equal(t, "sa-cloud-function@prj-secure-cloud-function-25de.iam.gserviceaccount.com", cf.ServiceConfig.ServiceAccountEmail)
+	assert.Equal(t, 120, cf.ServiceConfig.TimeoutSeconds)
+	assert.Equal(t, 2, cf.ServiceConfig.MaxInstanceCount)
+	assert.True(t, cf.ServiceConfig.AllTrafficOnLatestRevision)
+	assert.Equal(t, "prj-secure-cloud-function-25de", cf.ServiceConfig.EnvironmentVariables["PROJECT_ID"])
+
+	if assert.NotNil(t, cf.EventTrigger) {
+		assert.Equal(t, "google.cloud.audit.log.v1.written", cf.EventTrigger.EventType)
+		assert.Len(t, cf.EventTrigger.EventFilters, 3)
+		filter, ok := cf.EventTrigger.EventFilter("resourceName")
+		assert.True(t, ok)
+		assert.Equal(t, "match-path-pattern", filter.Operator)
+		_, ok = cf.EventTrigger.EventFilter("bucket")
+		assert.False(t, ok)
+	}
+}
+
+// TestCloudFunctionRoundTrip fails when function_describe.json has a field the model does not know about.
+func TestCloudFunctionRoundTrip(t *testing.T) {
+	data, err := os.ReadFile(functionDescribePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := ParseCloudFunction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := gjson.ParseBytes(data)
+	got := gjson.ParseBytes(encoded)
+	assert.ElementsMatch(t, utils.GetTerminalJSONPaths(want), utils.GetTerminalJSONPaths(got))
+	for _, path := range utils.GetTerminalJSONPaths(want) {
+		assert.Equal(t, want.Get(path).String(), got.Get(path).String(), "value of %s should survive a round trip", path)
+	}
+}
+
+func TestParseCloudFunctionSecrets(t *testing.T) {
+	cf, err := ParseCloudFunction([]byte(`{
+		"state": "ACTIVE",
+		"serviceConfig": {
+			"secretEnvironmentVariables": [
+				{"key": "INSTANCE_PWD", "projectId": "prj-security", "secret": "sct-mysql", "version": "latest"}
+			],
+			"secretVolumes": [
+				{"mountPath": "/etc/secrets", "projectId": "prj-security", "secret": "sct-certs", "versions": [{"version": "1", "path": "client.pem"}]}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, ok := cf.ServiceConfig.SecretEnvironmentVariable("INSTANCE_PWD")
+	assert.True(t, ok)
+	assert.Equal(t, "sct-mysql", secret.Secret)
+	_, ok = cf.ServiceConfig.SecretEnvironmentVariable("MISSING")
+	assert.False(t, ok)
+	if assert.Len(t, cf.ServiceConfig.SecretVolumes, 1) {
+		assert.Equal(t, "/etc/secrets", cf.ServiceConfig.SecretVolumes[0].MountPath)
+		assert.Equal(t, []SecretVersion{{Version: "1", Path: "client.pem"}}, cf.ServiceConfig.SecretVolumes[0].Versions)
+	}
+	_, ok = cf.ServiceConfig.SecretVolume("/etc/secrets")
+	assert.True(t, ok)
+	_, ok = cf.ServiceConfig.SecretVolume("/etc/missing")
+	assert.False(t, ok)
+	assert.Nil(t, cf.EventTrigger)
+
+	_, err = ParseCloudFunction([]byte(`{"state": 1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// FirewallRule is the output of `gcloud compute firewall-rules describe`.
+type FirewallRule struct {
+	Name                  string            `json:"name,omitempty"`
+	Description           string            `json:"description,omitempty"`
+	Network               string            `json:"network,omitempty"`
+	Direction             string            `json:"direction,omitempty"`
+	Priority              int               `json:"priority,omitempty"`
+	Disabled              bool              `json:"disabled,omitempty"`
+	SourceRanges          []string          `json:"sourceRanges,omitempty"`
+	DestinationRanges     []string          `json:"destinationRanges,omitempty"`
+	SourceTags            []string          `json:"sourceTags,omitempty"`
+	TargetTags            []string          `json:"targetTags,omitempty"`
+	TargetServiceAccounts []string          `json:"targetServiceAccounts,omitempty"`
+	Allowed               []Protocols       `json:"allowed,omitempty"`
+	Denied                []Protocols       `json:"denied,omitempty"`
+	LogConfig             FirewallLogConfig `json:"logConfig,omitempty"`
+}
+
+type FirewallLogConfig struct {
+	Enable   bool   `json:"enable,omitempty"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// FirewallExpectation declares the expected state of a firewall rule.
+// Empty fields are not checked.
+type FirewallExpectation struct {
+	Name      string
+	Direction string
+	// LogEnabled requires logConfig.enable to be true.
+	LogEnabled bool
+	// DestinationRanges must be the destination ranges of the rule, in any order.
+	DestinationRanges []string
+	// Allowed and Denied must match the rule entries exactly, in order.
+	Allowed []Protocols
+	Denied  []Protocols
+	// NamingConvention requires the name to follow the FirewallRuleName convention
+	// and to agree with the priority, direction, action, protocol and ports of the rule.
+	NamingConvention bool
+}
+
+// ParseFirewallRule decodes the JSON document returned by `gcloud compute firewall-rules describe`.
+func ParseFirewallRule(data []byte) (FirewallRule, error) {
+	var r FirewallRule
+	if err := json.Unmarshal(data, &r); err != nil {
+		return FirewallRule{}, fmt.Errorf("error parsing firewall rule: %w", err)
+	}
+	return r, nil
+}
+
+// DescribeFirewallRule runs `gcloud compute firewall-rules describe` and decodes the result.
+func (g *GCloud) DescribeFirewallRule(t testing.TB, name, projectID string) FirewallRule {
+	op := g.Runf(t, "compute firewall-rules describe %s --project %s", name, projectID)
+	r, err := ParseFirewallRule([]byte(op.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// ListFirewallRules runs `gcloud compute firewall-rules list` for a network and decodes the result.
+func (g *GCloud) ListFirewallRules(t testing.TB, projectID, network string) []FirewallRule {
+	op := g.Runf(t, "compute firewall-rules list --project %s --filter network:%s", projectID, network)
+	var rules []FirewallRule
+	if err := json.Unmarshal([]byte(op.Raw), &rules); err != nil {
+		t.Fatalf("error parsing firewall rules: %v", err)
+	}
+	return rules
+}
+
+// AssertFirewallRules describes each expected rule in projectID and fails assert once per mismatched field.
+func (g *GCloud) AssertFirewallRules(t testing.TB, assert *assert.Assertions, projectID string, expectations ...FirewallExpectation) {
+	for _, e := range expectations {
+		for _, m := range e.Mismatches(g.DescribeFirewallRule(t, e.Name, projectID)) {
+			assert.Fail(fmt.Sprintf("firewall rule %s does not match its expectation.", e.Name), m)
+		}
+	}
+}
+
+// Mismatches returns every field of r that differs from the expectation.
+func (e FirewallExpectation) Mismatches(r FirewallRule) []string {
+	var m []string
+	if r.Name != e.Name {
+		m = append(m, fmt.Sprintf("name is %q, want %q", r.Name, e.Name))
+	}
+	if e.Direction != "" && r.Direction != e.Direction {
+		m = append(m, fmt.Sprintf("direction is %q, want %q", r.Direction, e.Direction))
+	}
+	if e.LogEnabled && !r.LogConfig.Enable {
+		m = append(m, "log configuration is not enabled")
+	}
+	if e.DestinationRanges != nil {
+		for _, dr := range r.DestinationRanges {
+			if !slices.Contains(e.DestinationRanges, dr) {
+				m = append(m, fmt.Sprintf("destination range %s is not in %v", dr, e.DestinationRanges))
+			}
+		}
+		for _, dr := range e.DestinationRanges {
+			if !slices.Contains(r.DestinationRanges, dr) {
+				m = append(m, fmt.Sprintf("destination range %s is missing from %v", dr, r.DestinationRanges))
+			}
+		}
+	}
+	if e.Allowed != nil {
+		m = append(m, protocolsMismatches("allowed", r.Allowed, e.Allowed)...)
+	}
+	if e.Denied != nil {
+		m = append(m, protocolsMismatches("denied", r.Denied, e.Denied)...)
+	}
+	if e.NamingConvention {
+		m = append(m, r.NameMismatches()...)
+	}
+	return m
+}
+
+func protocolsMismatches(field string, got, want []Protocols) []string {
+	if len(got) != len(want) {
+		return []string{fmt.Sprintf("%s has %d entries, want %d", field, len(got), len(want))}
+	}
+	var m []string
+	for i := range want {
+		if got[i].Protocol != want[i].Protocol {
+			m = append(m, fmt.Sprintf("%s.%d protocol is %q, want %q", field, i, got[i].Protocol, want[i].Protocol))
+		}
+		if !slices.Equal(got[i].Ports, want[i].Ports) {
+			m = append(m, fmt.Sprintf("%s.%d ports are %v, want %v", field, i, got[i].Ports, want[i].Ports))
+		}
+	}
+	return m
+}
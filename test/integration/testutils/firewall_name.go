@@ -0,0 +1,146 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	FirewallActionAllow = "allow"
+	FirewallActionDeny  = "deny"
+)
+
+var (
+	firewallDirectionCodes = map[string]string{"e": "EGRESS", "i": "INGRESS"}
+	firewallActionCodes    = map[string]string{"a": FirewallActionAllow, "d": FirewallActionDeny}
+	firewallPortPattern    = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// FirewallRuleName is a firewall rule name following the
+// fw-<environment>-<vpc type>-<vpc name>-<priority>-<direction>-<action>-<source>-<destination>-<protocol>[-<port>]
+// convention, for example fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443.
+// Port is empty when the rule applies to every port.
+type FirewallRuleName struct {
+	EnvironmentCode string
+	VPCType         string
+	VPCName         string
+	Priority        int
+	Direction       string
+	Action          string
+	Source          string
+	Destination     string
+	Protocol        string
+	Port            string
+}
+
+// ParseFirewallRuleName decodes a firewall rule name that follows the naming convention.
+func ParseFirewallRuleName(name string) (FirewallRuleName, error) {
+	parts := strings.Split(name, "-")
+	if len(parts) < 10 || parts[0] != "fw" {
+		return FirewallRuleName{}, fmt.Errorf("firewall rule name %s does not follow the fw-<environment>-<vpc type>-<vpc name>-<priority>-... convention", name)
+	}
+	n := FirewallRuleName{EnvironmentCode: parts[1], VPCType: parts[2], VPCName: parts[3]}
+	priority, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return FirewallRuleName{}, fmt.Errorf("firewall rule name %s has invalid priority %q", name, parts[4])
+	}
+	n.Priority = priority
+	var ok bool
+	if n.Direction, ok = firewallDirectionCodes[parts[5]]; !ok {
+		return FirewallRuleName{}, fmt.Errorf("firewall rule name %s has invalid direction %q", name, parts[5])
+	}
+	if n.Action, ok = firewallActionCodes[parts[6]]; !ok {
+		return FirewallRuleName{}, fmt.Errorf("firewall rule name %s has invalid action %q", name, parts[6])
+	}
+
+	// The source label may contain dashes, so the remaining fields are read from the end.
+	rest := parts[7:]
+	if firewallPortPattern.MatchString(rest[len(rest)-1]) {
+		n.Port = rest[len(rest)-1]
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) < 3 {
+		return FirewallRuleName{}, fmt.Errorf("firewall rule name %s is missing the source, destination or protocol", name)
+	}
+	n.Protocol = rest[len(rest)-1]
+	n.Destination = rest[len(rest)-2]
+	n.Source = strings.Join(rest[:len(rest)-2], "-")
+	return n, nil
+}
+
+// String encodes n using the naming convention.
+func (n FirewallRuleName) String() string {
+	parts := []string{"fw", n.EnvironmentCode, n.VPCType, n.VPCName, strconv.Itoa(n.Priority),
+		reverseLookup(firewallDirectionCodes, n.Direction), reverseLookup(firewallActionCodes, n.Action),
+		n.Source, n.Destination, n.Protocol}
+	if n.Port != "" {
+		parts = append(parts, n.Port)
+	}
+	return strings.Join(parts, "-")
+}
+
+// Mismatches returns every property encoded in n that r does not have.
+func (n FirewallRuleName) Mismatches(r FirewallRule) []string {
+	var m []string
+	if r.Priority != n.Priority {
+		m = append(m, fmt.Sprintf("name encodes priority %d, rule has %d", n.Priority, r.Priority))
+	}
+	if r.Direction != n.Direction {
+		m = append(m, fmt.Sprintf("name encodes direction %s, rule has %s", n.Direction, r.Direction))
+	}
+	entries := r.Allowed
+	if n.Action == FirewallActionDeny {
+		entries = r.Denied
+	}
+	if len(entries) == 0 {
+		m = append(m, fmt.Sprintf("name encodes action %s, rule has no %s entries", n.Action, n.Action))
+	}
+	for _, e := range entries {
+		if e.Protocol != n.Protocol {
+			m = append(m, fmt.Sprintf("name encodes protocol %s, rule has %s", n.Protocol, e.Protocol))
+		}
+		ports := []string{}
+		if n.Port != "" {
+			ports = []string{n.Port}
+		}
+		if !slices.Equal(e.Ports, ports) {
+			m = append(m, fmt.Sprintf("name encodes ports %v, rule has %v", ports, e.Ports))
+		}
+	}
+	return m
+}
+
+// NameMismatches parses the name of r and returns every property it encodes that r does not have.
+func (r FirewallRule) NameMismatches() []string {
+	n, err := ParseFirewallRuleName(r.Name)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return n.Mismatches(r)
+}
+
+func reverseLookup(codes map[string]string, value string) string {
+	for k, v := range codes {
+		if v == value {
+			return k
+		}
+	}
+	return value
+}
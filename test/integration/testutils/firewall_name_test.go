@@ -0,0 +1,78 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFirewallRuleName(t *testing.T) {
+	tests := []struct {
+		name string
+		want FirewallRuleName
+	}{
+		{
+			name: "fw-e-shared-restricted-65535-e-d-all-all-all",
+			want: FirewallRuleName{EnvironmentCode: "e", VPCType: "shared", VPCName: "restricted", Priority: 65535,
+				Direction: "EGRESS", Action: FirewallActionDeny, Source: "all", Destination: "all", Protocol: "all"},
+		},
+		{
+			name: "fw-e-shared-restricted-65534-e-a-allow-google-apis-all-tcp-443",
+			want: FirewallRuleName{EnvironmentCode: "e", VPCType: "shared", VPCName: "restricted", Priority: 65534,
+				Direction: "EGRESS", Action: FirewallActionAllow, Source: "allow-google-apis", Destination: "all", Protocol: "tcp", Port: "443"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFirewallRuleName(tt.name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.name, got.String())
+			}
+		})
+	}
+
+	for _, name := range []string{
+		"fw-e-shared-restricted-internal-server",
+		"fw-allow-tcp-443-egress-to-secure-web-proxy",
+		"fw-e-shared-restricted-100-x-a-all-all-tcp-443",
+		"fw-e-shared-restricted-100-e-a-all-tcp-443",
+	} {
+		_, err := ParseFirewallRuleName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestFirewallRuleNameMismatches(t *testing.T) {
+	rule, err := ParseFirewallRule([]byte(denyAllEgressRule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, rule.NameMismatches())
+
+	// A rule edited in place without being renamed.
+	rule.Priority = 1000
+	rule.Denied = nil
+	rule.Allowed = []Protocols{{Protocol: "tcp", Ports: []string{"443"}}}
+	assert.Equal(t, []string{
+		"name encodes priority 65535, rule has 1000",
+		"name encodes action deny, rule has no deny entries",
+	}, rule.NameMismatches())
+
+	rule.Name = "fw-e-shared-restricted-1000-e-a-all-all-tcp-8443"
+	assert.Equal(t, []string{"name encodes ports [8443], rule has [443]"}, rule.NameMismatches())
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const denyAllEgressRule = `{
+  "denied": [{"IPProtocol": "all"}],
+  "destinationRanges": ["0.0.0.0/0"],
+  "direction": "EGRESS",
+  "disabled": false,
+  "logConfig": {"enable": true, "metadata": "INCLUDE_ALL_METADATA"},
+  "name": "fw-e-shared-restricted-65535-e-d-all-all-all",
+  "priority": 65535
+}`
+
+func TestFirewallExpectationMismatches(t *testing.T) {
+	rule, err := ParseFirewallRule([]byte(denyAllEgressRule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 65535, rule.Priority)
+
+	tests := []struct {
+		name        string
+		expectation FirewallExpectation
+		want        []string
+	}{
+		{
+			name: "match",
+			expectation: FirewallExpectation{
+				Name:              "fw-e-shared-restricted-65535-e-d-all-all-all",
+				Direction:         "EGRESS",
+				LogEnabled:        true,
+				DestinationRanges: []string{"0.0.0.0/0"},
+				Denied:            []Protocols{{Protocol: "all"}},
+			},
+		},
+		{
+			name: "missing destination range",
+			expectation: FirewallExpectation{
+				Name:              "fw-e-shared-restricted-65535-e-d-all-all-all",
+				DestinationRanges: []string{"10.0.0.0/28", "0.0.0.0/0"},
+			},
+			want: []string{"destination range 10.0.0.0/28 is missing from [0.0.0.0/0]"},
+		},
+		{
+			name: "every mismatch",
+			expectation: FirewallExpectation{
+				Name:              "fw-e-shared-restricted-65535-e-d-all-all-all",
+				Direction:         "INGRESS",
+				DestinationRanges: []string{"10.0.0.0/28"},
+				Allowed:           []Protocols{{Protocol: "tcp", Ports: []string{"443"}}},
+				Denied:            []Protocols{{Protocol: "tcp", Ports: []string{"22"}}},
+			},
+			want: []string{
+				`direction is "EGRESS", want "INGRESS"`,
+				"destination range 0.0.0.0/0 is not in [10.0.0.0/28]",
+				"destination range 10.0.0.0/28 is missing from [0.0.0.0/0]",
+				"allowed has 0 entries, want 1",
+				`denied.0 protocol is "all", want "tcp"`,
+				"denied.0 ports are [], want [22]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.expectation.Mismatches(rule))
+		})
+	}
+}
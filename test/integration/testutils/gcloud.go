@@ -0,0 +1,242 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/cloud-foundation-toolkit/infra/blueprint-test/pkg/utils"
+	"github.com/tidwall/gjson"
+)
+
+// FixtureModeEnv selects how NewGCloud runs commands. Set it to "record" to save
+// every gcloud call, Terraform output and environment value of a live run to the fixture file.
+const FixtureModeEnv = "GCLOUD_FIXTURE_MODE"
+
+// LocationEnv overrides the location the secure examples deploy to. Terraform
+// reads it as the location variable, and FixturePath names the recorded fixture after it.
+const LocationEnv = "TF_VAR_location"
+
+type Mode string
+
+const (
+	ModeLive   Mode = "live"
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+// defaultCommonArgs are the arguments gcloud.Run appends when no common args are given.
+var defaultCommonArgs = []string{"--format", "json"}
+
+// Fixture is the content of a recorded verify stage.
+type Fixture struct {
+	Env     map[string]string `json:"env,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+	Calls   []Call            `json:"calls"`
+}
+
+// Call is a single gcloud invocation and its JSON result.
+type Call struct {
+	Command string          `json:"command"`
+	Output  json.RawMessage `json:"output"`
+}
+
+// GCloud runs the gcloud commands of a verify stage.
+// The zero value runs every command against the live environment.
+type GCloud struct {
+	mode    Mode
+	path    string
+	mu      sync.Mutex
+	fixture Fixture
+	calls   map[string]json.RawMessage
+}
+
+// NewGCloud returns a GCloud that runs live, or records to fixture when FixtureModeEnv is "record".
+func NewGCloud(t testing.TB, fixture string) *GCloud {
+	g := &GCloud{mode: ModeLive, path: fixture}
+	if Mode(os.Getenv(FixtureModeEnv)) != ModeRecord {
+		return g
+	}
+	g.mode = ModeRecord
+	g.fixture = Fixture{Env: map[string]string{}, Outputs: map[string]string{}}
+	g.calls = map[string]json.RawMessage{}
+	t.Cleanup(func() {
+		if err := g.save(); err != nil {
+			t.Errorf("error saving gcloud fixture %s: %v", g.path, err)
+		}
+	})
+	return g
+}
+
+// FixturePath returns the fixture in dir that a live run records to:
+// verify.json, or verify-<location>.json when LocationEnv is set.
+func FixturePath(dir string) string {
+	if location := os.Getenv(LocationEnv); location != "" {
+		return filepath.Join(dir, "verify-"+location+".json")
+	}
+	return filepath.Join(dir, "verify.json")
+}
+
+// Fixtures returns every fixture recorded in dir, one per location.
+func Fixtures(t testing.TB, dir string) []string {
+	fixtures, err := filepath.Glob(filepath.Join(dir, "verify*.json"))
+	if err != nil {
+		t.Fatalf("error listing gcloud fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no gcloud fixtures in %s", dir)
+	}
+	return fixtures
+}
+
+// NewReplayGCloud returns a GCloud that serves every command from a recorded fixture.
+func NewReplayGCloud(t testing.TB, fixture string) *GCloud {
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("error reading gcloud fixture: %v", err)
+	}
+	return newReplayGCloud(t, fixture, data)
+}
+
+func newReplayGCloud(t testing.TB, fixture string, data []byte) *GCloud {
+	g := &GCloud{mode: ModeReplay, path: fixture, calls: map[string]json.RawMessage{}}
+	if err := json.Unmarshal(data, &g.fixture); err != nil {
+		t.Fatalf("error parsing gcloud fixture %s: %v", fixture, err)
+	}
+	for _, c := range g.fixture.Calls {
+		g.calls[normalizeCommand(c.Command)] = c.Output
+	}
+	return g
+}
+
+// Mode returns how g runs commands.
+func (g *GCloud) Mode() Mode {
+	if g.mode == "" {
+		return ModeLive
+	}
+	return g.mode
+}
+
+// Runf formats and runs a gcloud command like gcloud.Runf.
+func (g *GCloud) Runf(t testing.TB, cmd string, args ...interface{}) gjson.Result {
+	return g.Run(t, utils.StringFromTextAndArgs(append([]interface{}{cmd}, args...)...), nil)
+}
+
+// Run runs a gcloud command with commonArgs like gcloud.Run with gcloud.WithCommonArgs.
+// A nil commonArgs uses the gcloud default of "--format json".
+func (g *GCloud) Run(t testing.TB, cmd string, commonArgs []string) gjson.Result {
+	if commonArgs == nil {
+		commonArgs = defaultCommonArgs
+	}
+	key := normalizeCommand(cmd + " " + strings.Join(commonArgs, " "))
+
+	switch g.Mode() {
+	case ModeReplay:
+		g.mu.Lock()
+		op, ok := g.calls[key]
+		g.mu.Unlock()
+		if !ok {
+			t.Fatalf("no recorded output for gcloud %s in %s", key, g.path)
+		}
+		return gjson.ParseBytes(op)
+	case ModeRecord:
+		op := gcloud.Run(t, cmd, gcloud.WithCommonArgs(commonArgs))
+		g.mu.Lock()
+		g.calls[key] = json.RawMessage(op.Raw)
+		g.mu.Unlock()
+		return op
+	default:
+		return gcloud.Run(t, cmd, gcloud.WithCommonArgs(commonArgs))
+	}
+}
+
+// Outputs wraps a Terraform output getter, such as TFBlueprintTest.GetStringOutput,
+// so outputs are recorded or replayed together with the gcloud calls.
+// In replay mode live may be nil.
+func (g *GCloud) Outputs(t testing.TB, live func(string) string) func(string) string {
+	return func(name string) string {
+		switch g.Mode() {
+		case ModeReplay:
+			v, ok := g.fixture.Outputs[name]
+			if !ok {
+				t.Fatalf("no recorded Terraform output %s in %s", name, g.path)
+			}
+			return v
+		case ModeRecord:
+			v := live(name)
+			g.mu.Lock()
+			g.fixture.Outputs[name] = v
+			g.mu.Unlock()
+			return v
+		default:
+			return live(name)
+		}
+	}
+}
+
+// ValFromEnv returns the value of an environment variable like utils.ValFromEnv.
+func (g *GCloud) ValFromEnv(t testing.TB, k string) string {
+	switch g.Mode() {
+	case ModeReplay:
+		v, ok := g.fixture.Env[k]
+		if !ok {
+			t.Fatalf("no recorded environment variable %s in %s", k, g.path)
+		}
+		return v
+	case ModeRecord:
+		v := utils.ValFromEnv(t, k)
+		g.mu.Lock()
+		g.fixture.Env[k] = v
+		g.mu.Unlock()
+		return v
+	default:
+		return utils.ValFromEnv(t, k)
+	}
+}
+
+// save writes the recorded calls to the fixture file sorted by command.
+func (g *GCloud) save() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	keys := make([]string, 0, len(g.calls))
+	for k := range g.calls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	g.fixture.Calls = make([]Call, 0, len(keys))
+	for _, k := range keys {
+		g.fixture.Calls = append(g.fixture.Calls, Call{Command: k, Output: g.calls[k]})
+	}
+	data, err := json.MarshalIndent(g.fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(g.path, append(data, '\n'), 0644)
+}
+
+// normalizeCommand collapses whitespace so formatting differences do not change the fixture key.
+func normalizeCommand(cmd string) string {
+	return strings.Join(strings.Fields(cmd), " ")
+}
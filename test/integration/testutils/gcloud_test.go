@@ -0,0 +1,82 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGCloudReplay(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "testdata", "verify.json")
+	rec := &GCloud{
+		mode: ModeRecord,
+		path: fixture,
+		fixture: Fixture{
+			Env:     map[string]string{"TF_VAR_org_id": "123456789"},
+			Outputs: map[string]string{"project_id": "prj-test"},
+		},
+		calls: map[string]json.RawMessage{
+			"functions describe fn --project prj-test --gen2 --region us-west1 --format json":                                             json.RawMessage(`{"state":"ACTIVE"}`),
+			"access-context-manager policies list --organization 123456789 --filter parent:organizations/123456789 --quiet --format json": json.RawMessage(`[{"name":"accessPolicies/987"}]`),
+			"alpha storage ls --buckets gs://bkt --project prj-test --json":                                                               json.RawMessage(`[{"metadata":{"name":"bkt"}}]`),
+		},
+	}
+	if err := rec.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	gc := NewReplayGCloud(t, fixture)
+	assert.Equal(t, ModeReplay, gc.Mode())
+	assert.Equal(t, "123456789", gc.ValFromEnv(t, "TF_VAR_org_id"))
+	assert.Equal(t, "prj-test", gc.Outputs(t, nil)("project_id"))
+	assert.Equal(t, "987", gc.GetOrgACMPolicyID(t, "123456789"))
+
+	cf := gc.DescribeCloudFunction(t, "fn", "prj-test", "us-west1")
+	assert.Equal(t, "ACTIVE", cf.State)
+
+	// Whitespace differences in the command do not change the fixture key.
+	op := gc.Run(t, "alpha storage ls  --buckets gs://bkt", []string{"--project", "prj-test", "--json"})
+	assert.Equal(t, "bkt", op.Array()[0].Get("metadata.name").String())
+}
+
+func TestFixturePath(t *testing.T) {
+	t.Setenv(LocationEnv, "")
+	assert.Equal(t, filepath.Join("testdata", "verify.json"), FixturePath("testdata"))
+
+	t.Setenv(LocationEnv, "europe-west1")
+	assert.Equal(t, filepath.Join("testdata", "verify-europe-west1.json"), FixturePath("testdata"))
+}
+
+func TestFixtures(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"verify.json", "verify-europe-west1.json", "plan.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "verify-europe-west1.json"), filepath.Join(dir, "verify.json")}, Fixtures(t, dir))
+}
+
+func TestGCloudZeroValueIsLive(t *testing.T) {
+	var gc GCloud
+	assert.Equal(t, ModeLive, gc.Mode())
+	t.Setenv(FixtureModeEnv, "")
+	assert.Equal(t, ModeLive, NewGCloud(t, "unused.json").Mode())
+}
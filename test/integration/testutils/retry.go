@@ -0,0 +1,59 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+var (
+	RetryableTransientErrors = map[string]string{
+		// Error code 409 for concurrent policy changes.
+		".*Error 409.*There were concurrent policy changes.*": "Concurrent policy changes.",
+
+		// API Rate limit exceeded errors can be retried.
+		".*rateLimitExceeded.*": "Rate limit exceeded.",
+
+		// Project deletion is eventually consistent. Even if google_project resources inside the folder are deleted there may be a deletion error.
+		".*FOLDER_TO_DELETE_NON_EMPTY_VIOLATION.*": "Failed to delete non empty folder.",
+
+		// Granting IAM Roles is eventually consistent.
+		".*Error 403.*Permission.*denied on resource.*": "Permission denied on resource.",
+
+		// Error 403: Compute Engine API has not been used in project {} before or it is disabled.
+		".*Error 403.*Compute Engine API has not been used in project.*": "Compute Engine API not enabled",
+
+		// Editing VPC Service Controls is eventually consistent.
+		".*Error 403.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*":    "Request is prohibited by organization's policy.",
+		".*Error code 7.*Request is prohibited by organization's policy.*vpcServiceControlsUniqueIdentifier.*": "Request is prohibited by organization's policy.",
+
+		// Google Storage Service Agent propagation issue.
+		".*Error 400.*Service account service-.*@gs-project-accounts.iam.gserviceaccount.com does not exist.*": "Google Storage Service Agent propagation issue",
+	}
+	// PermanentErrors are errors retrying does not fix. They are only used to
+	// classify failures, tft fails on them as on any error that is not retryable.
+	PermanentErrors = map[string]string{
+		// Organization policy constraints reject the configuration.
+		".*Error 400.*Constraint constraints/.* violated.*": "Organization policy constraint violated.",
+
+		// The resource was created outside of Terraform or by a previous run.
+		".*Error 409.*already exists.*": "Resource already exists.",
+
+		// Invalid request fields are configuration errors.
+		".*Error 400.*Invalid value for field.*": "Invalid field value.",
+
+		// The project has no billing account linked.
+		".*Error 403.*requires billing to be enabled.*": "Billing is not enabled.",
+
+		// Terraform configuration errors.
+		".*Error: (Unsupported argument|Missing required argument|Reference to undeclared).*": "Invalid Terraform configuration.",
+	}
+)
@@ -0,0 +1,77 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryableTransientErrors(t *testing.T) {
+	for pattern := range RetryableTransientErrors {
+		_, err := regexp.Compile(pattern)
+		assert.NoError(t, err, "pattern %q should compile", pattern)
+	}
+
+	tests := []struct {
+		name      string
+		log       string
+		retryable bool
+	}{
+		{
+			name:      "concurrent policy changes",
+			log:       "Error: Error applying IAM policy: Error 409: There were concurrent policy changes. Please retry the whole read-modify-write with exponential backoff.",
+			retryable: true,
+		},
+		{
+			name:      "rate limit",
+			log:       "googleapi: Error 403: Quota exceeded, rateLimitExceeded",
+			retryable: true,
+		},
+		{
+			name:      "compute api not enabled",
+			log:       "Error 403: Compute Engine API has not been used in project 123456 before or it is disabled.",
+			retryable: true,
+		},
+		{
+			name:      "vpc service controls",
+			log:       "Error 403: Request is prohibited by organization's policy. vpcServiceControlsUniqueIdentifier: abc123",
+			retryable: true,
+		},
+		{
+			name:      "storage service agent",
+			log:       "Error 400: Service account service-123@gs-project-accounts.iam.gserviceaccount.com does not exist.",
+			retryable: true,
+		},
+		{
+			name:      "invalid argument",
+			log:       "Error 400: Invalid value for field 'resource.name'",
+			retryable: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := false
+			for pattern := range RetryableTransientErrors {
+				if regexp.MustCompile(pattern).MatchString(tt.log) {
+					matched = true
+				}
+			}
+			assert.Equal(t, tt.retryable, matched)
+		})
+	}
+}
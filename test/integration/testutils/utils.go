@@ -0,0 +1,66 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutils holds the helpers shared by the blueprint integration tests.
+package testutils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// Protocols is an allowed or denied entry of a firewall rule.
+type Protocols struct {
+	Protocol string   `json:"IPProtocol"`
+	Ports    []string `json:"ports,omitempty"`
+}
+
+// GetLastSplitElement returns the part of value after the last sep, such as the
+// name at the end of a resource URL.
+func GetLastSplitElement(value string, sep string) string {
+	splitted := strings.Split(value, sep)
+	return splitted[len(splitted)-1]
+}
+
+// GetResultFieldStrSlice returns field of each result as a string.
+func GetResultFieldStrSlice(rs []gjson.Result, field string) []string {
+	s := make([]string, 0)
+	for _, r := range rs {
+		s = append(s, r.Get(field).String())
+	}
+	return s
+}
+
+// GetOrgACMPolicyID gets the Organization Access Context Manager Policy ID
+func (g *GCloud) GetOrgACMPolicyID(t testing.TB, orgID string) string {
+	filter := fmt.Sprintf("parent:organizations/%s", orgID)
+	id := g.Runf(t, "access-context-manager policies list --organization %s --filter %s --quiet", orgID, filter).Array()
+	return policyIDFromList(id)
+}
+
+// GetProjectNumber gets the number of a project.
+func (g *GCloud) GetProjectNumber(t testing.TB, projectID string) string {
+	return g.Runf(t, "projects describe %s", projectID).Get("projectNumber").String()
+}
+
+// policyIDFromList returns the ID of the first policy in a policies list result.
+func policyIDFromList(policies []gjson.Result) string {
+	if len(policies) == 0 {
+		return ""
+	}
+	return GetLastSplitElement(policies[0].Get("name").String(), "/")
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestGetLastSplitElement(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		sep   string
+		want  string
+	}{
+		{name: "resource name", value: "accessPolicies/123456", sep: "/", want: "123456"},
+		{name: "no separator", value: "123456", sep: "/", want: "123456"},
+		{name: "trailing separator", value: "projects/p/", sep: "/", want: ""},
+		{name: "empty value", value: "", sep: "/", want: ""},
+		{name: "other separator", value: "a:b:c", sep: ":", want: "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetLastSplitElement(tt.value, tt.sep))
+		})
+	}
+}
+
+func TestGetResultFieldStrSlice(t *testing.T) {
+	rs := gjson.Parse(`[{"name":"a","ports":["443"]},{"name":"b"},{"other":"c"}]`).Array()
+	assert.Equal(t, []string{"a", "b", ""}, GetResultFieldStrSlice(rs, "name"))
+	assert.Equal(t, []string{"443", "", ""}, GetResultFieldStrSlice(rs, "ports.0"))
+	assert.Empty(t, GetResultFieldStrSlice(nil, "name"))
+}
+
+func TestPolicyIDFromList(t *testing.T) {
+	policies := gjson.Parse(`[{"name":"accessPolicies/1234","title":"default"},{"name":"accessPolicies/5678"}]`).Array()
+	assert.Equal(t, "1234", policyIDFromList(policies))
+	assert.Equal(t, "", policyIDFromList(gjson.Parse(`[]`).Array()))
+}
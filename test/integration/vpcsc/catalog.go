@@ -0,0 +1,182 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vpcsc holds the catalog of services the VPC Service Controls
+// perimeter of the secure blueprints restricts, and compares it with deployed perimeters.
+package vpcsc
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed restricted_services.yaml
+var restrictedServices []byte
+
+// Service is an entry of the catalog.
+type Service struct {
+	Name       string `yaml:"name"`
+	Deprecated bool   `yaml:"deprecated,omitempty"`
+	Note       string `yaml:"note,omitempty"`
+}
+
+// Catalog is a versioned list of restricted services.
+type Catalog struct {
+	Version  int       `yaml:"version"`
+	Services []Service `yaml:"services"`
+}
+
+// Report is the difference between a catalog and the restricted services of a perimeter.
+type Report struct {
+	// Missing are the active catalog services the perimeter does not restrict.
+	Missing []string `json:"missing"`
+	// Extra are the services the perimeter restricts that are not in the catalog.
+	Extra []string `json:"extra"`
+	// Deprecated are the deprecated catalog services the perimeter still restricts.
+	Deprecated []string `json:"deprecated"`
+}
+
+// CatalogDiff is the difference between two versions of the catalog.
+type CatalogDiff struct {
+	Added      []string `json:"added"`
+	Removed    []string `json:"removed"`
+	Deprecated []string `json:"deprecated"`
+}
+
+// Default returns the catalog shipped with the tests.
+func Default() Catalog {
+	c, err := Parse(restrictedServices)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadFile reads a catalog file.
+func LoadFile(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a catalog.
+func Parse(data []byte) (Catalog, error) {
+	var c Catalog
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Catalog{}, fmt.Errorf("error parsing restricted services catalog: %w", err)
+	}
+	if c.Version < 1 {
+		return Catalog{}, fmt.Errorf("restricted services catalog has no version")
+	}
+	seen := map[string]bool{}
+	for _, s := range c.Services {
+		if !strings.HasSuffix(s.Name, ".googleapis.com") {
+			return Catalog{}, fmt.Errorf("restricted services catalog: %q is not a googleapis.com service", s.Name)
+		}
+		if seen[s.Name] {
+			return Catalog{}, fmt.Errorf("restricted services catalog: %s is listed twice", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return c, nil
+}
+
+// Names returns the names of the services that are not deprecated.
+func (c Catalog) Names() []string {
+	var names []string
+	for _, s := range c.Services {
+		if !s.Deprecated {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// Service returns the catalog entry of name.
+func (c Catalog) Service(name string) (Service, bool) {
+	for _, s := range c.Services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Service{}, false
+}
+
+// Compare reports how the restricted services of a perimeter differ from c.
+func (c Catalog) Compare(restricted []string) Report {
+	r := Report{}
+	for _, s := range c.Services {
+		in := slices.Contains(restricted, s.Name)
+		switch {
+		case s.Deprecated && in:
+			r.Deprecated = append(r.Deprecated, s.Name)
+		case !s.Deprecated && !in:
+			r.Missing = append(r.Missing, s.Name)
+		}
+	}
+	for _, name := range restricted {
+		if _, ok := c.Service(name); !ok {
+			r.Extra = append(r.Extra, name)
+		}
+	}
+	slices.Sort(r.Missing)
+	slices.Sort(r.Extra)
+	slices.Sort(r.Deprecated)
+	return r
+}
+
+// Diff returns what changed from old to c.
+func (c Catalog) Diff(old Catalog) CatalogDiff {
+	d := CatalogDiff{}
+	for _, s := range c.Services {
+		o, ok := old.Service(s.Name)
+		switch {
+		case !ok:
+			d.Added = append(d.Added, s.Name)
+		case s.Deprecated && !o.Deprecated:
+			d.Deprecated = append(d.Deprecated, s.Name)
+		}
+	}
+	for _, s := range old.Services {
+		if _, ok := c.Service(s.Name); !ok {
+			d.Removed = append(d.Removed, s.Name)
+		}
+	}
+	slices.Sort(d.Added)
+	slices.Sort(d.Removed)
+	slices.Sort(d.Deprecated)
+	return d
+}
+
+// Lines formats the report as one line per difference.
+func (r Report) Lines() []string {
+	var lines []string
+	for _, s := range r.Missing {
+		lines = append(lines, fmt.Sprintf("missing: %s is not restricted by the perimeter", s))
+	}
+	for _, s := range r.Extra {
+		lines = append(lines, fmt.Sprintf("extra: %s is restricted by the perimeter but not in the catalog", s))
+	}
+	for _, s := range r.Deprecated {
+		lines = append(lines, fmt.Sprintf("deprecated: %s is deprecated but still restricted by the perimeter", s))
+	}
+	return lines
+}
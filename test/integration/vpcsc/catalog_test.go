@@ -0,0 +1,99 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vpcsc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCatalog = `
+version: 2
+services:
+  - name: bigquery.googleapis.com
+  - name: cloudfunctions.googleapis.com
+  - name: clouddebugger.googleapis.com
+    deprecated: true
+    note: Cloud Debugger was shut down.
+`
+
+func TestDefault(t *testing.T) {
+	c := Default()
+	assert.GreaterOrEqual(t, c.Version, 1)
+	assert.Contains(t, c.Names(), "cloudfunctions.googleapis.com")
+	assert.NotContains(t, c.Names(), "clouddebugger.googleapis.com")
+	s, ok := c.Service("gameservices.googleapis.com")
+	assert.True(t, ok)
+	assert.True(t, s.Deprecated)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: testCatalog},
+		{name: "no version", data: "services:\n  - name: bigquery.googleapis.com\n", wantErr: "restricted services catalog has no version"},
+		{name: "not a service", data: "version: 1\nservices:\n  - name: bigquery\n", wantErr: `restricted services catalog: "bigquery" is not a googleapis.com service`},
+		{name: "duplicate", data: "version: 1\nservices:\n  - name: bigquery.googleapis.com\n  - name: bigquery.googleapis.com\n", wantErr: "restricted services catalog: bigquery.googleapis.com is listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c, err := Parse([]byte(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.Compare([]string{"storage.googleapis.com", "clouddebugger.googleapis.com", "bigquery.googleapis.com"})
+	assert.Equal(t, Report{
+		Missing:    []string{"cloudfunctions.googleapis.com"},
+		Extra:      []string{"storage.googleapis.com"},
+		Deprecated: []string{"clouddebugger.googleapis.com"},
+	}, r)
+	assert.Equal(t, []string{
+		"missing: cloudfunctions.googleapis.com is not restricted by the perimeter",
+		"extra: storage.googleapis.com is restricted by the perimeter but not in the catalog",
+		"deprecated: clouddebugger.googleapis.com is deprecated but still restricted by the perimeter",
+	}, r.Lines())
+	assert.Empty(t, c.Compare(c.Names()).Lines())
+}
+
+func TestDiff(t *testing.T) {
+	old, err := Parse([]byte("version: 1\nservices:\n  - name: clouddebugger.googleapis.com\n  - name: bigquery.googleapis.com\n  - name: gameservices.googleapis.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse([]byte(testCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, CatalogDiff{
+		Added:      []string{"cloudfunctions.googleapis.com"},
+		Removed:    []string{"gameservices.googleapis.com"},
+		Deprecated: []string{"clouddebugger.googleapis.com"},
+	}, c.Diff(old))
+}